@@ -0,0 +1,162 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/Jubris-Knifes/wgj25-back/service"
+)
+
+type Service interface {
+	Sessions(ctx context.Context) ([]models.SessionInfo, error)
+	KickPlayer(ctx context.Context, playerID int) error
+	ForceStartRound(ctx context.Context) error
+	SkipPhase(ctx context.Context) error
+	ResetScores(ctx context.Context) error
+	EndGame(ctx context.Context) error
+	PauseGame(ctx context.Context) error
+	ResumeGame(ctx context.Context) error
+}
+
+type handler struct {
+	svc   Service
+	log   *slog.Logger
+	token string
+}
+
+// New returns the admin HTTP API. Every request must carry the configured
+// token as a bearer token; with no token configured the API rejects
+// everything.
+func New(logger *slog.Logger, svc Service, token string) http.Handler {
+	h := &handler{
+		svc:   svc,
+		log:   logger,
+		token: token,
+	}
+
+	if token == "" {
+		logger.Warn("admin token not set, admin API disabled")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/sessions", h.listSessions)
+	mux.HandleFunc("POST /admin/players/{id}/kick", h.kickPlayer)
+	mux.HandleFunc("POST /admin/round/start", h.forceStartRound)
+	mux.HandleFunc("POST /admin/phase/skip", h.skipPhase)
+	mux.HandleFunc("POST /admin/scores/reset", h.resetScores)
+	mux.HandleFunc("POST /admin/game/end", h.endGame)
+	mux.HandleFunc("POST /admin/game/pause", h.pauseGame)
+	mux.HandleFunc("POST /admin/game/resume", h.resumeGame)
+	mux.Handle("GET /admin/metrics", expvar.Handler())
+
+	return h.authenticate(mux)
+}
+
+func (h *handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if h.token == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			h.log.WarnContext(r.Context(), "unauthorized admin request",
+				"remote_address", r.RemoteAddr,
+				"path", r.URL.Path,
+			)
+			h.writeError(w, r, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+
+		h.log.InfoContext(r.Context(), "admin request", "method", r.Method, "path", r.URL.Path)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *handler) listSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.svc.Sessions(r.Context())
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusOK, sessions)
+}
+
+func (h *handler) kickPlayer(w http.ResponseWriter, r *http.Request) {
+	playerID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, errors.New("invalid player id"))
+		return
+	}
+
+	h.handleAction(w, r, func(ctx context.Context) error {
+		return h.svc.KickPlayer(ctx, playerID)
+	})
+}
+
+func (h *handler) forceStartRound(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, h.svc.ForceStartRound)
+}
+
+func (h *handler) skipPhase(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, h.svc.SkipPhase)
+}
+
+func (h *handler) resetScores(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, h.svc.ResetScores)
+}
+
+func (h *handler) endGame(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, h.svc.EndGame)
+}
+
+func (h *handler) pauseGame(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, h.svc.PauseGame)
+}
+
+func (h *handler) resumeGame(w http.ResponseWriter, r *http.Request) {
+	h.handleAction(w, r, h.svc.ResumeGame)
+}
+
+func (h *handler) handleAction(w http.ResponseWriter, r *http.Request, action func(context.Context) error) {
+	if err := action(r.Context()); err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrPlayerNotAtTable):
+		h.writeError(w, r, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrGameNotRunning),
+		errors.Is(err, service.ErrInvalidPlayerCount),
+		errors.Is(err, service.ErrGameAlreadyPaused),
+		errors.Is(err, service.ErrGameNotPaused),
+		errors.Is(err, service.ErrNoTimedPhase):
+		h.writeError(w, r, http.StatusConflict, err)
+	default:
+		h.log.ErrorContext(r.Context(), "admin request failed", "error", err, "path", r.URL.Path)
+		h.writeError(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+func (h *handler) writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	h.writeJSON(w, r, status, map[string]string{"error": err.Error()})
+}
+
+func (h *handler) writeJSON(w http.ResponseWriter, r *http.Request, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.log.ErrorContext(r.Context(), "failed to write admin response", "error", err)
+	}
+}
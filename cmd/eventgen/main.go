@@ -0,0 +1,99 @@
+// eventgen generates TypeScript types and a JSON Schema for the websocket
+// events declared in the models package, so the frontend doesn't have to copy
+// them by hand.
+//
+// Every `FooEvent = Envelope[Foo]` alias becomes an event; its type is the
+// `EventTypeFoo` constant when there is one. Run with -check to fail when the
+// generated files are out of date.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const (
+	typescriptFile = "events.ts"
+	schemaFile     = "events.schema.json"
+)
+
+func main() {
+	modelsDir := flag.String("models", "models", "directory of the models package")
+	outDir := flag.String("out", "schema", "directory to write the generated files to")
+	check := flag.Bool("check", false, "fail if the generated files are out of date instead of writing them")
+	flag.Parse()
+
+	files, err := generate(*modelsDir)
+	if err != nil {
+		slog.Error("failed to generate event files", "error", err)
+		os.Exit(1)
+	}
+
+	if *check {
+		stale := staleFiles(*outDir, files)
+		for _, path := range stale {
+			slog.Error("generated file is out of date, run go generate ./models", "file", path)
+		}
+
+		if len(stale) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		slog.Error("failed to create output directory", "error", err)
+		os.Exit(1)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(*outDir, name)
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			slog.Error("failed to write generated file", "file", path, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Println("generated", filepath.Join(*outDir, typescriptFile), filepath.Join(*outDir, schemaFile))
+}
+
+// generate returns the content of the generated files, by name.
+func generate(modelsDir string) (map[string][]byte, error) {
+	pkg, err := parseModels(modelsDir)
+	if err != nil {
+		return nil, fmt.Errorf("parse models: %w", err)
+	}
+
+	typescript, err := generateTypescript(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("generate typescript: %w", err)
+	}
+
+	schema, err := generateSchema(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("generate json schema: %w", err)
+	}
+
+	return map[string][]byte{
+		typescriptFile: typescript,
+		schemaFile:     schema,
+	}, nil
+}
+
+// staleFiles lists the files in outDir that don't match what was generated.
+func staleFiles(outDir string, files map[string][]byte) []string {
+	var stale []string
+	for name, content := range files {
+		path := filepath.Join(outDir, name)
+		current, err := os.ReadFile(path)
+		if err != nil || !bytes.Equal(current, content) {
+			stale = append(stale, path)
+		}
+	}
+
+	return stale
+}
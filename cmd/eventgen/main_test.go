@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+// TestGeneratedFilesUpToDate runs the -check of go generate ./models, so
+// event files that drifted from the models fail the build.
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	files, err := generate("../../models")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range staleFiles("../../schema", files) {
+		t.Errorf("%s is out of date, run go generate ./models", path)
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const eventTypeName = "EventType"
+
+type kind int
+
+const (
+	kindString kind = iota
+	kindInteger
+	kindNumber
+	kindBoolean
+	kindAny
+	kindArray
+	kindNullable
+	kindNamed
+)
+
+type typeRef struct {
+	kind kind
+	name string
+	elem *typeRef
+}
+
+type (
+	field struct {
+		name     string
+		typ      typeRef
+		optional bool
+	}
+
+	structDef struct {
+		name   string
+		fields []field
+	}
+
+	enumDef struct {
+		name   string
+		values []string
+	}
+
+	event struct {
+		name      string
+		payload   typeRef
+		eventType string
+	}
+
+	constant struct {
+		name  string
+		value string
+	}
+
+	modelPackage struct {
+		structs   map[string]*structDef
+		enums     map[string]*enumDef
+		basics    map[string]typeRef
+		events    []event
+		constants []constant
+	}
+)
+
+// parseModels reads the Go files of the models package, without type checking
+// them, and collects the events and every type they reference.
+func parseModels(dir string) (*modelPackage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	pkg := &modelPackage{
+		structs: map[string]*structDef{},
+		enums:   map[string]*enumDef{},
+		basics:  map[string]typeRef{},
+	}
+
+	aliases := map[string]ast.Expr{}
+	var aliasOrder []string
+	eventTypes := map[string]string{}
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.TypeParams != nil || !spec.Name.IsExported() {
+						continue
+					}
+
+					if spec.Assign != 0 {
+						if index, ok := spec.Type.(*ast.IndexExpr); ok && isIdent(index.X, "Envelope") {
+							aliases[spec.Name.Name] = index.Index
+							aliasOrder = append(aliasOrder, spec.Name.Name)
+						}
+						continue
+					}
+
+					if err := pkg.addType(spec); err != nil {
+						return nil, fmt.Errorf("%s: %w", fset.Position(spec.Pos()), err)
+					}
+				case *ast.ValueSpec:
+					if genDecl.Tok != token.CONST {
+						continue
+					}
+					pkg.addConstant(spec, eventTypes)
+				}
+			}
+		}
+	}
+
+	for _, alias := range aliasOrder {
+		payload, err := pkg.resolve(aliases[alias])
+		if err != nil {
+			return nil, fmt.Errorf("event %s: %w", alias, err)
+		}
+
+		pkg.events = append(pkg.events, event{
+			name:      alias,
+			payload:   payload,
+			eventType: eventTypes["EventType"+strings.TrimSuffix(alias, "Event")],
+		})
+	}
+
+	return pkg, nil
+}
+
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+func (p *modelPackage) addType(spec *ast.TypeSpec) error {
+	switch typ := spec.Type.(type) {
+	case *ast.StructType:
+		def := &structDef{name: spec.Name.Name}
+		for _, f := range typ.Fields.List {
+			if len(f.Names) == 0 {
+				return fmt.Errorf("embedded fields are not supported in %s", spec.Name.Name)
+			}
+
+			for _, name := range f.Names {
+				if !name.IsExported() {
+					continue
+				}
+
+				jsonName, optional := name.Name, false
+				if f.Tag != nil {
+					tag, err := strconv.Unquote(f.Tag.Value)
+					if err != nil {
+						return err
+					}
+
+					parts := strings.Split(reflect.StructTag(tag).Get("json"), ",")
+					if parts[0] == "-" {
+						continue
+					}
+					if parts[0] != "" {
+						jsonName = parts[0]
+					}
+					optional = slices.Contains(parts[1:], "omitempty")
+				}
+
+				ref, err := p.resolveLater(f.Type)
+				if err != nil {
+					return fmt.Errorf("%s.%s: %w", spec.Name.Name, name.Name, err)
+				}
+
+				def.fields = append(def.fields, field{name: jsonName, typ: ref, optional: optional})
+			}
+		}
+		p.structs[def.name] = def
+	case *ast.Ident:
+		basic, err := basicType(typ.Name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", spec.Name.Name, err)
+		}
+		p.basics[spec.Name.Name] = basic
+	}
+
+	return nil
+}
+
+func (p *modelPackage) addConstant(spec *ast.ValueSpec, eventTypes map[string]string) {
+	for i, name := range spec.Names {
+		if !name.IsExported() || i >= len(spec.Values) {
+			continue
+		}
+
+		lit, ok := spec.Values[i].(*ast.BasicLit)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case spec.Type == nil && lit.Kind == token.INT:
+			p.constants = append(p.constants, constant{name: name.Name, value: lit.Value})
+		case spec.Type != nil && lit.Kind == token.STRING:
+			typeName, ok := spec.Type.(*ast.Ident)
+			if !ok {
+				continue
+			}
+
+			value, err := strconv.Unquote(lit.Value)
+			if err != nil {
+				continue
+			}
+
+			enum, ok := p.enums[typeName.Name]
+			if !ok {
+				enum = &enumDef{name: typeName.Name}
+				p.enums[typeName.Name] = enum
+			}
+			enum.values = append(enum.values, value)
+
+			if typeName.Name == eventTypeName {
+				eventTypes[name.Name] = value
+			}
+		}
+	}
+}
+
+// resolveLater maps a field type to a typeRef. Local named types are only
+// resolved once every declaration has been read, see resolve.
+func (p *modelPackage) resolveLater(expr ast.Expr) (typeRef, error) {
+	switch typ := expr.(type) {
+	case *ast.Ident:
+		if basic, err := basicType(typ.Name); err == nil {
+			return basic, nil
+		}
+		return typeRef{kind: kindNamed, name: typ.Name}, nil
+	case *ast.ArrayType:
+		if typ.Len != nil {
+			return typeRef{}, fmt.Errorf("fixed size arrays are not supported")
+		}
+		elem, err := p.resolveLater(typ.Elt)
+		if err != nil {
+			return typeRef{}, err
+		}
+		return typeRef{kind: kindArray, elem: &elem}, nil
+	case *ast.StarExpr:
+		elem, err := p.resolveLater(typ.X)
+		if err != nil {
+			return typeRef{}, err
+		}
+		return typeRef{kind: kindNullable, elem: &elem}, nil
+	case *ast.SelectorExpr, *ast.InterfaceType:
+		return typeRef{kind: kindAny}, nil
+	case *ast.StructType:
+		if typ.Fields.NumFields() == 0 {
+			return typeRef{kind: kindAny}, nil
+		}
+	}
+
+	return typeRef{}, fmt.Errorf("unsupported type %T", expr)
+}
+
+func (p *modelPackage) resolve(expr ast.Expr) (typeRef, error) {
+	ref, err := p.resolveLater(expr)
+	if err != nil {
+		return typeRef{}, err
+	}
+
+	return ref, p.check(ref)
+}
+
+// check makes sure every named type reachable from ref was declared.
+func (p *modelPackage) check(ref typeRef) error {
+	switch ref.kind {
+	case kindArray, kindNullable:
+		return p.check(*ref.elem)
+	case kindNamed:
+		if _, ok := p.structs[ref.name]; ok {
+			return nil
+		}
+		if _, ok := p.enums[ref.name]; ok {
+			return nil
+		}
+		if _, ok := p.basics[ref.name]; ok {
+			return nil
+		}
+		return fmt.Errorf("unknown type %s", ref.name)
+	}
+
+	return nil
+}
+
+func basicType(name string) (typeRef, error) {
+	switch name {
+	case "string":
+		return typeRef{kind: kindString}, nil
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return typeRef{kind: kindInteger}, nil
+	case "float32", "float64":
+		return typeRef{kind: kindNumber}, nil
+	case "bool":
+		return typeRef{kind: kindBoolean}, nil
+	case "any":
+		return typeRef{kind: kindAny}, nil
+	}
+
+	return typeRef{}, fmt.Errorf("unsupported basic type %s", name)
+}
+
+// reachable returns the names of the structs and enums used by the events,
+// sorted by name. EventType is always included.
+func (p *modelPackage) reachable() (structs []string, enums []string) {
+	seen := map[string]bool{eventTypeName: true}
+	enums = append(enums, eventTypeName)
+
+	var visit func(ref typeRef)
+	visit = func(ref typeRef) {
+		switch ref.kind {
+		case kindArray, kindNullable:
+			visit(*ref.elem)
+		case kindNamed:
+			if seen[ref.name] {
+				return
+			}
+			seen[ref.name] = true
+
+			if def, ok := p.structs[ref.name]; ok {
+				structs = append(structs, ref.name)
+				for _, f := range def.fields {
+					visit(f.typ)
+				}
+			} else if _, ok := p.enums[ref.name]; ok {
+				enums = append(enums, ref.name)
+			}
+		}
+	}
+
+	for _, e := range p.events {
+		visit(e.payload)
+	}
+
+	slices.Sort(structs)
+	slices.Sort(enums)
+
+	return structs, enums
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+func generateSchema(pkg *modelPackage) ([]byte, error) {
+	defs := map[string]any{}
+
+	structs, enums := pkg.reachable()
+
+	for _, name := range enums {
+		defs[name] = map[string]any{
+			"type": "string",
+			"enum": pkg.enums[name].values,
+		}
+	}
+
+	for _, name := range structs {
+		def := pkg.structs[name]
+
+		properties := map[string]any{}
+		required := []string{}
+		for _, f := range def.fields {
+			properties[f.name] = pkg.schemaType(f.typ)
+			if !f.optional {
+				required = append(required, f.name)
+			}
+		}
+
+		defs[name] = map[string]any{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	}
+
+	events := make([]any, 0, len(pkg.events))
+	for _, e := range pkg.events {
+		eventType := any(ref(eventTypeName))
+		if e.eventType != "" {
+			eventType = map[string]any{"const": e.eventType}
+		}
+
+		defs[e.name] = map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"type":       eventType,
+				"event_data": pkg.schemaType(e.payload),
+				"request_id": map[string]any{"type": "string"},
+			},
+			"required": []string{"type", "event_data"},
+		}
+		events = append(events, ref(e.name))
+	}
+
+	schema := map[string]any{
+		"$schema": schemaDialect,
+		"title":   "Event",
+		"oneOf":   events,
+		"$defs":   defs,
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/$defs/" + name}
+}
+
+func (p *modelPackage) schemaType(typ typeRef) map[string]any {
+	switch typ.kind {
+	case kindString:
+		return map[string]any{"type": "string"}
+	case kindInteger:
+		return map[string]any{"type": "integer"}
+	case kindNumber:
+		return map[string]any{"type": "number"}
+	case kindBoolean:
+		return map[string]any{"type": "boolean"}
+	case kindArray:
+		return map[string]any{"type": "array", "items": p.schemaType(*typ.elem)}
+	case kindNullable:
+		return map[string]any{"oneOf": []any{p.schemaType(*typ.elem), map[string]any{"type": "null"}}}
+	case kindNamed:
+		if _, ok := p.structs[typ.name]; ok {
+			return ref(typ.name)
+		}
+		if _, ok := p.enums[typ.name]; ok {
+			return ref(typ.name)
+		}
+		return p.schemaType(p.basics[typ.name])
+	}
+
+	return map[string]any{}
+}
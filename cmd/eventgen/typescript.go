@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const header = "// Code generated by eventgen from the models package. DO NOT EDIT.\n"
+
+func generateTypescript(pkg *modelPackage) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString(header)
+
+	if len(pkg.constants) > 0 {
+		b.WriteString("\n")
+		for _, c := range pkg.constants {
+			fmt.Fprintf(&b, "export const %s = %s;\n", c.name, c.value)
+		}
+	}
+
+	structs, enums := pkg.reachable()
+
+	for _, name := range enums {
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "export type %s =\n", name)
+		for i, value := range pkg.enums[name].values {
+			fmt.Fprintf(&b, "  | %q", value)
+			if i == len(pkg.enums[name].values)-1 {
+				b.WriteString(";")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	for _, name := range structs {
+		def := pkg.structs[name]
+
+		b.WriteString("\n")
+		if len(def.fields) == 0 {
+			fmt.Fprintf(&b, "export type %s = Record<string, never>;\n", name)
+			continue
+		}
+
+		fmt.Fprintf(&b, "export interface %s {\n", name)
+		for _, f := range def.fields {
+			optional := ""
+			if f.optional {
+				optional = "?"
+			}
+			fmt.Fprintf(&b, "  %s%s: %s;\n", f.name, optional, pkg.typescriptType(f.typ))
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, e := range pkg.events {
+		eventType := eventTypeName
+		if e.eventType != "" {
+			eventType = fmt.Sprintf("%q", e.eventType)
+		}
+
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "export interface %s {\n", e.name)
+		fmt.Fprintf(&b, "  type: %s;\n", eventType)
+		fmt.Fprintf(&b, "  event_data: %s;\n", pkg.typescriptType(e.payload))
+		b.WriteString("}\n")
+	}
+
+	b.WriteString("\nexport type Event =\n")
+	for i, e := range pkg.events {
+		fmt.Fprintf(&b, "  | %s", e.name)
+		if i == len(pkg.events)-1 {
+			b.WriteString(";")
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n// Events sent by clients may carry a request_id, echoed back in the ack or\n")
+	b.WriteString("// error event that answers them.\n")
+	b.WriteString("export type WithRequestID<E extends Event> = E & { request_id?: string };\n")
+
+	return []byte(b.String()), nil
+}
+
+func (p *modelPackage) typescriptType(ref typeRef) string {
+	switch ref.kind {
+	case kindString:
+		return "string"
+	case kindInteger, kindNumber:
+		return "number"
+	case kindBoolean:
+		return "boolean"
+	case kindArray:
+		elem := p.typescriptType(*ref.elem)
+		if ref.elem.kind == kindNullable {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case kindNullable:
+		return p.typescriptType(*ref.elem) + " | null"
+	case kindNamed:
+		if _, ok := p.structs[ref.name]; ok {
+			return ref.name
+		}
+		if _, ok := p.enums[ref.name]; ok {
+			return ref.name
+		}
+		return p.typescriptType(p.basics[ref.name])
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,59 @@
+package codec
+
+import (
+	"encoding/json"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Codec encodes the events exchanged with a session. Binary codecs are sent as
+// binary websocket frames, the rest as text frames.
+type Codec interface {
+	Name() string
+	Binary() bool
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+const (
+	NameJSON = "json"
+	NameCBOR = "cbor"
+)
+
+var (
+	JSON Codec = jsonCodec{}
+	CBOR Codec = cborCodec{}
+
+	codecs = map[string]Codec{
+		NameJSON: JSON,
+		NameCBOR: CBOR,
+	}
+)
+
+// Negotiate picks the first codec we support from the client's preferences,
+// falling back to JSON.
+func Negotiate(preferred []string) Codec {
+	for _, name := range preferred {
+		if c, ok := codecs[name]; ok {
+			return c
+		}
+	}
+
+	return JSON
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                       { return NameJSON }
+func (jsonCodec) Binary() bool                       { return false }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// cborCodec uses the json struct tags of the models, so both encodings share
+// the same field names.
+type cborCodec struct{}
+
+func (cborCodec) Name() string                       { return NameCBOR }
+func (cborCodec) Binary() bool                       { return true }
+func (cborCodec) Marshal(v any) ([]byte, error)      { return cbor.Marshal(v) }
+func (cborCodec) Unmarshal(data []byte, v any) error { return cbor.Unmarshal(data, v) }
@@ -13,6 +13,62 @@ type (
 		ReservedName string `env:"ZROK_RESERVED_NAME"`
 	}
 
+	join struct {
+		// TerminalQR prints a QR code of the join URL once the share is up,
+		// so players in the room can scan it off the server's screen.
+		TerminalQR bool `env:"JOIN_TERMINAL_QR" envDefault:"true"`
+		// FrontendURL is where the game's frontend is hosted. Join links open
+		// it with the server's address and the room code in the query.
+		FrontendURL string `env:"JOIN_FRONTEND_URL"`
+	}
+
+	database struct {
+		Path string `env:"DATABASE_PATH" envDefault:":memory:"`
+	}
+
+	admin struct {
+		Token string `env:"ADMIN_TOKEN"`
+	}
+
+	limits struct {
+		MessagesPerSecond float64 `env:"LIMIT_MESSAGES_PER_SECOND" envDefault:"10"`
+		MessageBurst      int     `env:"LIMIT_MESSAGE_BURST" envDefault:"20"`
+		MaxMessageBytes   int64   `env:"LIMIT_MAX_MESSAGE_BYTES" envDefault:"4096"`
+		MaxViolations     int     `env:"LIMIT_MAX_VIOLATIONS" envDefault:"5"`
+		// ViolationWindowSeconds is how long a turned down message counts
+		// towards MaxViolations.
+		ViolationWindowSeconds int `env:"LIMIT_VIOLATION_WINDOW_SECONDS" envDefault:"60"`
+
+		OutboundQueueDepth int `env:"LIMIT_OUTBOUND_QUEUE_DEPTH" envDefault:"64"`
+	}
+
+	latency struct {
+		PingIntervalMilliseconds int `env:"LATENCY_PING_INTERVAL_MILLISECONDS" envDefault:"5000"`
+		FairMilliseconds         int `env:"LATENCY_FAIR_MILLISECONDS" envDefault:"150"`
+		PoorMilliseconds         int `env:"LATENCY_POOR_MILLISECONDS" envDefault:"400"`
+		// MaxGraceMilliseconds caps how much longer the server waits for the
+		// input of players with a slow connection. 0 disables it.
+		MaxGraceMilliseconds int `env:"LATENCY_MAX_GRACE_MILLISECONDS" envDefault:"500"`
+	}
+
+	afk struct {
+		// TimeoutLimit is how many phases in a row a player can let time out
+		// before the bot takes their seat.
+		TimeoutLimit int `env:"AFK_TIMEOUT_LIMIT" envDefault:"2"`
+		// AutoPickStrategy picks for players who let a phase time out: random,
+		// best_hand or least_valuable.
+		AutoPickStrategy string `env:"AUTO_PICK_STRATEGY" envDefault:"random"`
+	}
+
+	// table holds the defaults of the settings each table starts with.
+	// TABLE_SIZE replaces MAX_PLAYERS, which is no longer read.
+	table struct {
+		Size         int    `env:"TABLE_SIZE" envDefault:"4"`
+		Rounds       int    `env:"TABLE_ROUNDS" envDefault:"0"`
+		TargetPoints int    `env:"TABLE_TARGET_POINTS" envDefault:"0"`
+		Ruleset      string `env:"TABLE_RULESET" envDefault:"classic"`
+	}
+
 	timeouts struct {
 		PlayerChooseBidMilliseconds    int `env:"TIMEOUT_PLAYER_CHOOSE_BID_MILLISECONDS" envDefault:"5000"`
 		ShowBidMilliseconds            int `env:"TIMEOUT_SHOW_BID_MILLISECONDS" envDefault:"1500"`
@@ -43,11 +99,17 @@ type (
 	}
 
 	config struct {
-		MaxPlayers int `env:"MAX_PLAYERS" envDefault:"100"`
-		Zrok       zrok
-		Port       int `env:"PORT" envDefault:"8080"`
-		Timeouts   timeouts
-		Points     points
+		Zrok     zrok
+		Join     join
+		Database database
+		Admin    admin
+		Limits   limits
+		Latency  latency
+		Port     int `env:"PORT" envDefault:"8080"`
+		Table    table
+		Timeouts timeouts
+		AFK      afk
+		Points   points
 	}
 )
 
@@ -28,6 +28,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -38,9 +39,12 @@ import (
 
 	"database/sql"
 
+	"github.com/Jubris-Knifes/wgj25-back/admin"
 	"github.com/Jubris-Knifes/wgj25-back/config"
+	"github.com/Jubris-Knifes/wgj25-back/qrcode"
 	"github.com/Jubris-Knifes/wgj25-back/repository"
 	"github.com/Jubris-Knifes/wgj25-back/service"
+	"github.com/Jubris-Knifes/wgj25-back/transport"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/sqlite"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -56,21 +60,54 @@ func main() {
 	endChan := make(chan os.Signal, 1)
 	signal.Notify(endChan, syscall.SIGTERM, syscall.SIGINT)
 
-	db, err := sql.Open("sqlite", ":memory:")
+	db, err := sql.Open("sqlite", config.Get().Database.Path)
 	if err != nil {
 		panic(err)
 	}
+	// Every connection to :memory: gets a database of its own.
+	db.SetMaxOpenConns(1)
 	runMigrations(db)
 
 	m := melody.New()
 	repo := repository.New(logger, db)
-	svc := service.New(logger, repo, m)
+	sessions := transport.NewRegistry()
+	if _, ok := os.LookupEnv("MAX_PLAYERS"); ok {
+		logger.Warn("MAX_PLAYERS is no longer read, set TABLE_SIZE instead")
+	}
+	svc, err := service.New(logger, repo, sessions, transport.NewMelody(logger, sessions))
+	if err != nil {
+		panic(err)
+	}
+	if err := svc.Restore(context.Background()); err != nil {
+		panic(err)
+	}
 
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		logger.Info("AAAHHHHH", "headers", r.Header)
 	})
+	mux.Handle("/admin/", admin.New(logger, svc, config.Get().Admin.Token))
+	mux.HandleFunc("GET /join.png", func(w http.ResponseWriter, r *http.Request) {
+		joinURL := svc.JoinURL()
+		if joinURL == "" {
+			http.Error(w, "no join URL, configure JOIN_FRONTEND_URL", http.StatusServiceUnavailable)
+			return
+		}
+
+		png, err := qrcode.PNG(joinURL)
+		if err != nil {
+			logger.Error("failed to render join QR code", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-store")
+		if _, err := w.Write(png); err != nil {
+			logger.Error("failed to write join QR code", "error", err)
+		}
+	})
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		// CORS headers
 
@@ -99,12 +136,20 @@ func main() {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
-		logger.Info("New connection", "remote_address", r.RemoteAddr, "headers", r.Header, "url", r.URL)
+		logger.Info("New connection", "remote_address", r.RemoteAddr, "url", r.URL)
+		logger.Debug("New connection headers", "remote_address", r.RemoteAddr, "headers", r.Header)
 
 		m.HandleRequest(w, r)
 	})
 
 	m.Upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+	m.Config.MaxMessageSize = config.Get().Limits.MaxMessageBytes
+	m.HandleError(func(s *melody.Session, err error) {
+		logger.Warn("Session error",
+			"remote_address", s.RemoteAddr().String(),
+			"error", err,
+		)
+	})
 	m.HandleConnect(func(s *melody.Session) {
 		logger.Info("New connection established",
 			"remote_address", s.RemoteAddr().String(),
@@ -119,19 +164,28 @@ func main() {
 		)
 		svc.ClosedConnection(s)
 	})
+	m.HandlePong(func(s *melody.Session) {
+		transport.Pong(s)
+	})
+	m.HandleSentMessage(func(s *melody.Session, _ []byte) {
+		transport.MessageSent(s)
+	})
+	m.HandleSentMessageBinary(func(s *melody.Session, _ []byte) {
+		transport.MessageSent(s)
+	})
 	m.HandleMessage(func(s *melody.Session, data []byte) {
-		logger.Info("New message received",
+		logger.Debug("New message received",
 			"remote_address", s.RemoteAddr().String(),
-			"keys", s.Keys,
+			"size", len(data),
 		)
 		svc.HandleMessage(s, data)
 	})
 	m.HandleMessageBinary(func(s *melody.Session, data []byte) {
-		logger.Info("New message binary received",
+		logger.Debug("New message binary received",
 			"remote_address", s.RemoteAddr().String(),
-			"keys", s.Keys,
+			"size", len(data),
 		)
-		svc.HandleMessage(s, data)
+		svc.HandleMessageBinary(s, data)
 	})
 
 	port := config.Get().Port
@@ -178,9 +232,37 @@ func main() {
 		}
 	}()
 
-	logger.Info("Share created", "frontend_endpoints", FrontendEndpoint)
+	if config.Get().Join.TerminalQR {
+		svc.OnRoomChange(printJoinQR)
+	}
+	svc.SetFrontendEndpoint(FrontendEndpoint)
+	logger.Info("Share created", "frontend_endpoints", FrontendEndpoint, "join_url", svc.JoinURL())
+	if svc.JoinURL() == "" {
+		logger.Warn("no join URL, set JOIN_FRONTEND_URL to the frontend's address to show join links and QR codes")
+	}
 
 	<-endChan
+
+	svc.Shutdown(context.Background())
+}
+
+// printJoinQR draws the join URL as a QR code on the terminal, with the room
+// code under it for players typing it in by hand. It runs again every time a
+// hub creates a room.
+func printJoinQR(joinURL, roomCode string) {
+	if joinURL != "" {
+		code, err := qrcode.Terminal(joinURL)
+		if err != nil {
+			logger.Error("failed to render join QR code", "error", err)
+			return
+		}
+
+		fmt.Print(code)
+		fmt.Println(joinURL)
+	}
+	if roomCode != "" {
+		fmt.Println("Room code:", roomCode)
+	}
 }
 
 func runMigrations(db *sql.DB) {
@@ -197,6 +279,15 @@ func runMigrations(db *sql.DB) {
 		panic(err)
 	}
 
+	// 000001 did not parse as first shipped. Migrations run in a transaction,
+	// so databases it left dirty have none of its tables and can run it again.
+	if version, dirty, err := m.Version(); err == nil && dirty && version == 1 {
+		logger.Warn("rerunning initial migration left dirty")
+		if err := m.Force(-1); err != nil {
+			panic(err)
+		}
+	}
+
 	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
 		panic(err)
 	}
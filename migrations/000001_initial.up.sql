@@ -13,13 +13,4 @@ CREATE TABLE player_hand (
     PRIMARY KEY (player_id, card_id, card_type, is_real)
 );
 
-CREATE UNIQUE INDEX idx_player_card_unique_card ON player_hand (card_id, card_type, is_real);
-
-CREATE TABLE current_player (
-    current_player_id INTEGER
-)
-
-CREATE TABLE player_scores (
-    player_id INTEGER PRIMARY KEY,
-    points INTEGER NOT NULL DEFAULT 0,
-)
\ No newline at end of file
+CREATE UNIQUE INDEX idx_player_card_unique_card ON player_hand (card_id, card_type, is_real);
\ No newline at end of file
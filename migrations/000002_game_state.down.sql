@@ -0,0 +1,5 @@
+DROP TABLE game_state;
+
+DROP INDEX idx_players_resume_token;
+
+ALTER TABLE players DROP COLUMN resume_token;
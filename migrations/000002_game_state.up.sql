@@ -0,0 +1,9 @@
+ALTER TABLE players ADD COLUMN resume_token TEXT;
+
+CREATE UNIQUE INDEX idx_players_resume_token ON players (resume_token);
+
+CREATE TABLE game_state (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    state TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
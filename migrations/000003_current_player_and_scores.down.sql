@@ -0,0 +1,3 @@
+DROP TABLE player_scores;
+
+DROP TABLE current_player;
@@ -0,0 +1,9 @@
+-- These were part of 000001, which did not parse as first shipped.
+CREATE TABLE IF NOT EXISTS current_player (
+    current_player_id INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS player_scores (
+    player_id INTEGER PRIMARY KEY,
+    points INTEGER NOT NULL DEFAULT 0
+);
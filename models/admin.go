@@ -0,0 +1,22 @@
+package models
+
+type (
+	SessionInfo struct {
+		RemoteAddress string        `json:"remote_address"`
+		Role          Role          `json:"role"`
+		PlayerID      *int          `json:"player_id"`
+		QueueLength   int           `json:"queue_length"`
+		Latency       *LatencyStats `json:"latency,omitempty"`
+	}
+
+	// LatencyStats are the round trips of a session's last websocket pings.
+	LatencyStats struct {
+		LastMilliseconds    int64 `json:"last_milliseconds"`
+		AverageMilliseconds int64 `json:"average_milliseconds"`
+		JitterMilliseconds  int64 `json:"jitter_milliseconds"`
+		MaxMilliseconds     int64 `json:"max_milliseconds"`
+		Samples             int   `json:"samples"`
+		// Timeouts counts the pings that went unanswered.
+		Timeouts int `json:"timeouts"`
+	}
+)
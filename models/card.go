@@ -6,6 +6,16 @@ type Card struct {
 	IsReal bool `json:"is_real"`
 }
 
+// CardFace is a card as seen by someone who can't tell whether it is real.
+type CardFace struct {
+	ID   int `json:"id"`
+	Type int `json:"type"`
+}
+
+func (c Card) Face() CardFace {
+	return CardFace{ID: c.ID, Type: c.Type}
+}
+
 var AvailableRealCards = []Card{
 	{ID: 1, Type: 1, IsReal: true},
 	{ID: 2, Type: 1, IsReal: true},
@@ -1,5 +1,7 @@
 package models
 
+//go:generate go run ../cmd/eventgen -models . -out ../schema
+
 import "encoding/json"
 
 type (
@@ -10,11 +12,41 @@ type (
 		EventData EventData `json:"event_data"`
 	}
 	EnvelopeIn struct {
-		Type      EventType       `json:"type"`
-		EventData json.RawMessage `json:"event_data"`
+		Type      EventType  `json:"type"`
+		EventData RawMessage `json:"event_data"`
+		RequestID string     `json:"request_id,omitempty"`
 	}
+
+	// RawMessage is an event payload left undecoded, in whatever encoding the
+	// session speaks.
+	RawMessage []byte
 )
 
+func (e Envelope[EventData]) EventType() EventType {
+	return e.Type
+}
+
+func (m RawMessage) MarshalJSON() ([]byte, error) {
+	return json.RawMessage(m).MarshalJSON()
+}
+
+func (m *RawMessage) UnmarshalJSON(data []byte) error {
+	*m = append((*m)[0:0], data...)
+	return nil
+}
+
+func (m RawMessage) MarshalCBOR() ([]byte, error) {
+	if m == nil {
+		return []byte{0xf6}, nil // null
+	}
+	return m, nil
+}
+
+func (m *RawMessage) UnmarshalCBOR(data []byte) error {
+	*m = append((*m)[0:0], data...)
+	return nil
+}
+
 const (
 	EventTypeEndOfRound  EventType = "end_of_round"
 	EventTypeUpdateScore EventType = "update_score"
@@ -24,24 +56,29 @@ const (
 type (
 	EndOfRoundEvent = Envelope[EndOfRound]
 	EndOfRound      struct {
-		Timeout int64 `json:"timeout"`
+		Timeout  int64 `json:"timeout"`
+		Deadline int64 `json:"deadline"`
 	}
 
 	UpdateScoreEvent = Envelope[UpdateScore]
 	UpdateScore      struct {
-		Timeout int64       `json:"timeout"`
-		Scores  []HandScore `json:"scores"`
+		Timeout  int64       `json:"timeout"`
+		Deadline int64       `json:"deadline"`
+		Scores   []HandScore `json:"scores"`
 	}
+	// HandScore shows a hand on the table. Only the points it scored tell
+	// how many of its cards were fakes.
 	HandScore struct {
-		PlayerID int    `json:"player_id"`
-		Points   int    `json:"points"`
-		Cards    []Card `json:"cards"`
+		PlayerID int        `json:"player_id"`
+		Points   int        `json:"points"`
+		Cards    []CardFace `json:"cards"`
 	}
 
 	SumScoreEvent = Envelope[SumScore]
 	SumScore      struct {
-		Timeout int64         `json:"timeout"`
-		Scores  []ScoreChange `json:"scores"`
+		Timeout  int64         `json:"timeout"`
+		Deadline int64         `json:"deadline"`
+		Scores   []ScoreChange `json:"scores"`
 	}
 	ScoreChange struct {
 		PlayerID int `json:"player_id"`
@@ -58,6 +95,7 @@ type (
 	PrepareForNextTurnEvent = Envelope[PrepareForNextTurn]
 	PrepareForNextTurn      struct {
 		Timeout    int64 `json:"timeout"`
+		Deadline   int64 `json:"deadline"`
 		NextBidder int   `json:"next_bidder"`
 	}
 )
@@ -77,6 +115,12 @@ type PlayerOffer struct {
 	Card     Card `json:"card"`
 }
 
+// OfferFace is an offer as seen by the player choosing between offers.
+type OfferFace struct {
+	PlayerID int      `json:"player_id"`
+	Card     CardFace `json:"card"`
+}
+
 type (
 	PlayerChooseOfferEvent = Envelope[PlayerChooseOffer]
 	PlayerChooseOffer      struct {
@@ -86,18 +130,21 @@ type (
 	SelectOfferChosenEvent = Envelope[SelectOfferChosen]
 	SelectOfferChosen      struct {
 		Timeout  int64 `json:"timeout"`
+		Deadline int64 `json:"deadline"`
 		PlayerID int   `json:"player_id"`
 	}
 
 	SelectOfferChoicesEvent = Envelope[SelectOfferChoices]
 	SelectOfferChoices      struct {
-		Offers  []PlayerOffer `json:"offers"`
-		Timeout int64         `json:"timeout"`
+		Offers   []OfferFace `json:"offers"`
+		Timeout  int64       `json:"timeout"`
+		Deadline int64       `json:"deadline"`
 	}
 	OffersFinishedEvent = Envelope[OffersFinished]
 	OffersFinished      struct {
-		Offers  []PlayerOffer `json:"offers"`
-		Timeout int64         `json:"timeout"`
+		Offers   []OfferFace `json:"offers"`
+		Timeout  int64       `json:"timeout"`
+		Deadline int64       `json:"deadline"`
 	}
 
 	MadeOfferEvent = Envelope[MadeOffer]
@@ -110,6 +157,7 @@ type (
 	ChooseOffer struct {
 		PlayerIDs []int `json:"player_ids"`
 		Timeout   int64 `json:"timeout"`
+		Deadline  int64 `json:"deadline"`
 	}
 
 	OfferSelectedEvent = Envelope[OfferSelected]
@@ -130,6 +178,7 @@ type (
 
 	ShowBackOfCardBid struct {
 		Timetout int64 `json:"timeout"`
+		Deadline int64 `json:"deadline"`
 	}
 
 	ChooseBidEvent = Envelope[ChooseBid]
@@ -137,6 +186,7 @@ type (
 	ChooseBid struct {
 		PlayerID       int   `json:"player_id"`
 		Timeout        int64 `json:"timeout"`
+		Deadline       int64 `json:"deadline"`
 		CanFinishRound bool  `json:"can_finish_round"`
 	}
 
@@ -144,8 +194,9 @@ type (
 
 	ShowBidSelectedEvent = Envelope[ShowBidSelected]
 	ShowBidSelected      struct {
-		Card    Card  `json:"card"`
-		Timeout int64 `json:"timeout"`
+		Card     Card  `json:"card"`
+		Timeout  int64 `json:"timeout"`
+		Deadline int64 `json:"deadline"`
 	}
 
 	BidSelected struct {
@@ -183,14 +234,30 @@ const (
 type (
 	SetNameEvent = Envelope[SetName]
 
+	// SetName joins the table. RoomCode is required once the hub has created
+	// a room.
 	SetName struct {
-		Name string `json:"name"`
+		Name     string `json:"name"`
+		RoomCode string `json:"room_code,omitempty"`
 	}
 
 	SetNameResponseEvent = Envelope[SetNameResponse]
 
+	// SetNameResponse carries the token the player sends in a resume event
+	// to get their seat back after losing the connection or a server restart.
 	SetNameResponse struct {
-		AssignedPlayerID int `json:"assigned_player_id"`
+		AssignedPlayerID int    `json:"assigned_player_id"`
+		ResumeToken      string `json:"resume_token,omitempty"`
+	}
+)
+
+const EventTypeResume EventType = "resume"
+
+type (
+	ResumeEvent = Envelope[Resume]
+
+	Resume struct {
+		ResumeToken string `json:"resume_token"`
 	}
 )
 
@@ -204,3 +271,279 @@ type (
 		Name     string `json:"name"`
 	}
 )
+
+const (
+	EventTypeLobbyRoster EventType = "lobby_roster"
+	EventTypeSetReady    EventType = "set_ready"
+	EventTypeStartGame   EventType = "start_game"
+)
+
+type (
+	LobbyRosterEvent = Envelope[LobbyRoster]
+
+	LobbyRoster struct {
+		HostPlayerID int      `json:"host_player_id,omitempty"`
+		Players      []Player `json:"players"`
+	}
+
+	SetReadyEvent = Envelope[SetReady]
+
+	SetReady struct {
+		Ready bool `json:"ready"`
+	}
+
+	// StartGame is sent by the host or the hub once every player is ready.
+	StartGameEvent = Envelope[StartGame]
+	StartGame      struct{}
+)
+
+const EventTypeGameEnded EventType = "game_ended"
+
+type (
+	GameEndedEvent = Envelope[GameEnded]
+
+	GameEnded struct {
+		Reason string `json:"reason"`
+	}
+)
+
+const EventTypeStateSnapshot EventType = "state_snapshot"
+
+type (
+	StateSnapshotEvent = Envelope[StateSnapshot]
+
+	// StateSnapshot is everything a client joining mid-game needs to catch
+	// up, limited to what its role may see.
+	StateSnapshot struct {
+		Phase           Phase        `json:"phase"`
+		Paused          bool         `json:"paused"`
+		Timeout         int64        `json:"timeout,omitempty"`
+		Deadline        int64        `json:"deadline,omitempty"`
+		Roster          []Player     `json:"roster"`
+		Scores          []Score      `json:"scores"`
+		CurrentPlayerID *int         `json:"current_player_id,omitempty"`
+		Hand            []Card       `json:"hand,omitempty"`
+		BidPlaced       bool         `json:"bid_placed"`
+		Bid             *Card        `json:"bid,omitempty"`
+		PlayersOffered  []int        `json:"players_offered,omitempty"`
+		Offers          []OfferFace  `json:"offers,omitempty"`
+		OwnOffer        *PlayerOffer `json:"own_offer,omitempty"`
+		AFKPlayerIDs    []int        `json:"afk_player_ids,omitempty"`
+		HostPlayerID    int          `json:"host_player_id,omitempty"`
+		Settings        Settings     `json:"settings"`
+		Room            *Room        `json:"room,omitempty"`
+	}
+
+	Player struct {
+		PlayerID  int    `json:"player_id" db:"player_id"`
+		Name      string `json:"name" db:"player_name"`
+		Connected bool   `json:"connected" db:"-"`
+		Ready     bool   `json:"ready" db:"-"`
+	}
+)
+
+const EventTypePlayerAFK EventType = "player_afk"
+
+type (
+	PlayerAFKEvent = Envelope[PlayerAFK]
+
+	// PlayerAFK tells the table a player stopped playing and the server plays
+	// for them, or that they are back.
+	PlayerAFK struct {
+		PlayerID int  `json:"player_id"`
+		AFK      bool `json:"afk"`
+	}
+)
+
+const EventTypeAutoAction EventType = "auto_action"
+
+type (
+	AutoActionKind   string
+	AutoActionReason string
+)
+
+const (
+	AutoActionBid         AutoActionKind = "bid"
+	AutoActionEndRound    AutoActionKind = "end_round"
+	AutoActionOffer       AutoActionKind = "offer"
+	AutoActionChooseOffer AutoActionKind = "choose_offer"
+
+	AutoActionReasonTimeout AutoActionReason = "timeout"
+	AutoActionReasonAFK     AutoActionReason = "afk"
+)
+
+type (
+	AutoActionEvent = Envelope[AutoAction]
+
+	// AutoAction tells a player and the table what the server played for the
+	// player, and why. Card is only sent to the player.
+	AutoAction struct {
+		PlayerID      int              `json:"player_id"`
+		Action        AutoActionKind   `json:"action"`
+		Reason        AutoActionReason `json:"reason"`
+		Card          *Card            `json:"card,omitempty"`
+		OfferPlayerID int              `json:"offer_player_id,omitempty"`
+	}
+)
+
+const EventTypeGameError EventType = "game_error"
+
+type (
+	GameErrorEvent = Envelope[GameError]
+
+	// GameError tells clients the game loop failed. The failed step is played
+	// again if Retrying, otherwise the game is ended.
+	GameError struct {
+		Step     string `json:"step"`
+		Retrying bool   `json:"retrying"`
+	}
+)
+
+const (
+	EventTypePauseGame   EventType = "pause_game"
+	EventTypeResumeGame  EventType = "resume_game"
+	EventTypeGamePaused  EventType = "game_paused"
+	EventTypeGameResumed EventType = "game_resumed"
+)
+
+type (
+	PauseGameEvent = Envelope[PauseGame]
+	PauseGame      struct{}
+
+	ResumeGameEvent = Envelope[ResumeGame]
+	ResumeGame      struct{}
+
+	GamePausedEvent = Envelope[GamePaused]
+
+	GamePaused struct {
+		RemainingTimeout int64 `json:"remaining_timeout"`
+	}
+
+	GameResumedEvent = Envelope[GameResumed]
+
+	GameResumed struct {
+		Timeout  int64 `json:"timeout"`
+		Deadline int64 `json:"deadline"`
+	}
+)
+
+const (
+	EventTypePing EventType = "ping"
+	EventTypePong EventType = "pong"
+)
+
+// Deadlines in timed events are Unix times in milliseconds, on the server's
+// clock. Clients estimate how far their clock is from it with ping and pong,
+// and count down to the same instant whenever the event reaches them.
+type (
+	PingEvent = Envelope[Ping]
+
+	Ping struct {
+		ClientTime int64 `json:"client_time"`
+	}
+
+	PongEvent = Envelope[Pong]
+
+	Pong struct {
+		ClientTime int64 `json:"client_time"`
+		ServerTime int64 `json:"server_time"`
+	}
+)
+
+const EventTypeConnectionQuality EventType = "connection_quality"
+
+type ConnectionQualityLevel string
+
+const (
+	ConnectionQualityGood ConnectionQualityLevel = "good"
+	ConnectionQualityFair ConnectionQualityLevel = "fair"
+	ConnectionQualityPoor ConnectionQualityLevel = "poor"
+)
+
+type (
+	ConnectionQualityEvent = Envelope[ConnectionQuality]
+
+	// ConnectionQuality tells the hub how well each connected player's
+	// connection is doing, so a slow network can be told apart from a slow
+	// player.
+	ConnectionQuality struct {
+		Players []PlayerConnection `json:"players"`
+	}
+
+	PlayerConnection struct {
+		PlayerID            int                    `json:"player_id"`
+		Quality             ConnectionQualityLevel `json:"quality"`
+		AverageMilliseconds int64                  `json:"average_milliseconds"`
+		JitterMilliseconds  int64                  `json:"jitter_milliseconds"`
+	}
+)
+
+const (
+	EventTypeHello         EventType = "hello"
+	EventTypeHelloResponse EventType = "hello_response"
+)
+
+type (
+	HelloEvent = Envelope[Hello]
+
+	Hello struct {
+		ProtocolVersion int      `json:"protocol_version"`
+		Role            Role     `json:"role"`
+		Encodings       []string `json:"encodings,omitempty"`
+	}
+
+	HelloResponseEvent = Envelope[HelloResponse]
+
+	HelloResponse struct {
+		Accepted        bool   `json:"accepted"`
+		ProtocolVersion int    `json:"protocol_version"`
+		Role            Role   `json:"role"`
+		Encoding        string `json:"encoding"`
+		Reason          string `json:"reason,omitempty"`
+	}
+)
+
+const EventTypeError EventType = "error"
+
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidJSON        ErrorCode = "invalid_json"
+	ErrorCodeInvalidEncoding    ErrorCode = "invalid_encoding"
+	ErrorCodeUnknownEvent       ErrorCode = "unknown_event"
+	ErrorCodeInvalidPayload     ErrorCode = "invalid_payload"
+	ErrorCodeNotAllowed         ErrorCode = "not_allowed"
+	ErrorCodeWrongPhase         ErrorCode = "wrong_phase"
+	ErrorCodeRateLimited        ErrorCode = "rate_limited"
+	ErrorCodeDuplicateAction    ErrorCode = "duplicate_action"
+	ErrorCodePlayerCountTooHigh ErrorCode = "player_count_too_high"
+	ErrorCodeInvalidPlayerCount ErrorCode = "invalid_player_count"
+	ErrorCodePlayersNotReady    ErrorCode = "players_not_ready"
+	ErrorCodeGameAlreadyRunning ErrorCode = "game_already_running"
+	ErrorCodeUnknownRoom        ErrorCode = "unknown_room"
+	ErrorCodeNameTaken          ErrorCode = "name_taken"
+	ErrorCodeGameNotRunning     ErrorCode = "game_not_running"
+	ErrorCodeGameAlreadyPaused  ErrorCode = "game_already_paused"
+	ErrorCodeGameNotPaused      ErrorCode = "game_not_paused"
+	ErrorCodeInternal           ErrorCode = "internal_error"
+)
+
+type (
+	ErrorEvent = Envelope[ErrorDetails]
+
+	ErrorDetails struct {
+		Code      ErrorCode `json:"code"`
+		Message   string    `json:"message"`
+		RequestID string    `json:"request_id,omitempty"`
+	}
+)
+
+const EventTypeAck EventType = "ack"
+
+type (
+	AckEvent = Envelope[Ack]
+
+	Ack struct {
+		RequestID string `json:"request_id"`
+	}
+)
@@ -0,0 +1,25 @@
+package models
+
+// ResumeStep is the step of the game loop a game picks up from, after a
+// failure or a restart.
+type ResumeStep string
+
+const (
+	ResumeRound      ResumeStep = "round"
+	ResumeTurn       ResumeStep = "turn"
+	ResumeNextTurn   ResumeStep = "next_turn"
+	ResumeEndOfRound ResumeStep = "end_of_round"
+)
+
+// GameState is what a game picks up from after a restart, besides the hands,
+// scores and current player already in their own tables. A restored game
+// plays its Resume step again from the start, and once it gets back to Phase
+// its timer only runs for the TimeLeft it had, in milliseconds.
+type GameState struct {
+	Resume       ResumeStep `json:"resume"`
+	Phase        Phase      `json:"phase"`
+	TimeLeft     int64      `json:"time_left,omitempty"`
+	PlayerIDs    []int      `json:"player_ids"`
+	RoundsPlayed int        `json:"rounds_played"`
+	Settings     Settings   `json:"settings"`
+}
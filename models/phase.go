@@ -0,0 +1,17 @@
+package models
+
+// Phase is the step of the game loop the table is in.
+type Phase string
+
+const (
+	PhaseLobby           Phase = "lobby"
+	PhaseDealing         Phase = "dealing"
+	PhaseBid             Phase = "bid"
+	PhaseShowBid         Phase = "show_bid"
+	PhaseOffers          Phase = "offers"
+	PhaseShowOffers      Phase = "show_offers"
+	PhaseChooseOffer     Phase = "choose_offer"
+	PhaseShowChosenOffer Phase = "show_chosen_offer"
+	PhaseNextTurn        Phase = "next_turn"
+	PhaseEndOfRound      Phase = "end_of_round"
+)
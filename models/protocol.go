@@ -0,0 +1,26 @@
+package models
+
+// ProtocolVersion is the version of the event protocol spoken by this server.
+// Event names and payloads are part of the protocol, including the odd casing
+// of choose_Offer and Offer_selected, so any change to them must bump it.
+const (
+	ProtocolVersion    = 1
+	MinProtocolVersion = 1
+)
+
+type Role string
+
+const (
+	RolePlayer    Role = "player"
+	RoleHub       Role = "hub"
+	RoleSpectator Role = "spectator"
+)
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RolePlayer, RoleHub, RoleSpectator:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,31 @@
+package models
+
+// RoomCodeLength is the number of characters of a room code. Codes only use
+// consonants that can't be mistaken for one another, so they're easy to read
+// off a screen and type on a phone.
+const (
+	RoomCodeLength   = 4
+	RoomCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+)
+
+const (
+	EventTypeCreateRoom EventType = "create_room"
+	EventTypeRoom       EventType = "room"
+)
+
+type (
+	// CreateRoom is sent by a hub to open the table under a new room code,
+	// replacing the one it created before. Players joining from then on must
+	// send the code of one of the open rooms with their name.
+	CreateRoomEvent = Envelope[CreateRoom]
+	CreateRoom      struct{}
+
+	RoomEvent = Envelope[Room]
+
+	// Room is shown on the hub that created it so players can find the
+	// table. JoinURL is empty while no frontend is configured.
+	Room struct {
+		Code    string `json:"code"`
+		JoinURL string `json:"join_url,omitempty"`
+	}
+)
@@ -2,8 +2,8 @@ package models
 
 type (
 	Score struct {
-		PlayerID int
-		Points   int
+		PlayerID int `json:"player_id"`
+		Points   int `json:"points"`
 	}
 
 	UpdatedScore struct {
@@ -0,0 +1,113 @@
+package models
+
+type Ruleset string
+
+const (
+	// RulesetClassic deals five cards per player, four fakes among them.
+	RulesetClassic Ruleset = "classic"
+	// RulesetNoFakes deals four real cards per player.
+	RulesetNoFakes Ruleset = "no_fakes"
+)
+
+func (r Ruleset) IsValid() bool {
+	switch r {
+	case RulesetClassic, RulesetNoFakes:
+		return true
+	}
+
+	return false
+}
+
+// ClassicFakeCards is how many fakes the classic ruleset shuffles in the deck.
+const ClassicFakeCards = 4
+
+// HandSize is how many cards each player is dealt.
+func (r Ruleset) HandSize() int {
+	if r == RulesetClassic {
+		return 5
+	}
+
+	return 4
+}
+
+// DeckSize is how many cards the hands of a round are dealt from.
+func (r Ruleset) DeckSize() int {
+	if r == RulesetClassic {
+		return len(AvailableRealCards) + ClassicFakeCards
+	}
+
+	return len(AvailableRealCards)
+}
+
+const (
+	MinTableSize = 2
+	MaxTableSize = 4
+
+	MinTimeoutMilliseconds = 250
+	MaxTimeoutMilliseconds = 120000
+)
+
+type (
+	// Settings are the rules a table plays by. They start from the server's
+	// configuration and can be changed in the lobby.
+	Settings struct {
+		Timeouts     TimeoutSettings `json:"timeouts"`
+		Points       PointSettings   `json:"points"`
+		EndCondition EndCondition    `json:"end_condition"`
+		TableSize    int             `json:"table_size"`
+		Ruleset      Ruleset         `json:"ruleset"`
+	}
+
+	// TimeoutSettings are in milliseconds.
+	TimeoutSettings struct {
+		ChooseBid          int `json:"choose_bid"`
+		ShowBid            int `json:"show_bid"`
+		ChooseOffer        int `json:"choose_offer"`
+		ShowOffer          int `json:"show_offer"`
+		BetweenActions     int `json:"between_actions"`
+		OffersFinished     int `json:"offers_finished"`
+		ShowSelectedOffer  int `json:"show_selected_offer"`
+		PrepareForNextTurn int `json:"prepare_for_next_turn"`
+		EndOfRound         int `json:"end_of_round"`
+		UpdateScore        int `json:"update_score"`
+		SumScore           int `json:"sum_score"`
+	}
+
+	PointSettings struct {
+		FakePoker    int `json:"fake_poker"`
+		Poker        int `json:"poker"`
+		OneOfEach    int `json:"one_of_each"`
+		FullHouse    int `json:"full_house"`
+		ThreeOfAKind int `json:"three_of_a_kind"`
+		TwoPair      int `json:"two_pair"`
+		Pair         int `json:"pair"`
+
+		FakeOne   int `json:"fake_one"`
+		FakeTwo   int `json:"fake_two"`
+		FakeThree int `json:"fake_three"`
+	}
+
+	// EndCondition ends the game after Rounds rounds, or once a player has
+	// TargetPoints. Zero means no limit.
+	EndCondition struct {
+		Rounds       int `json:"rounds"`
+		TargetPoints int `json:"target_points"`
+	}
+)
+
+const (
+	EventTypeSettings       EventType = "settings"
+	EventTypeSettingsUpdate EventType = "settings_update"
+)
+
+type (
+	SettingsEvent = Envelope[Settings]
+
+	SettingsUpdateEvent = Envelope[SettingsUpdate]
+
+	// SettingsUpdate replaces the settings of the table. Only the host and
+	// the hub may send it, and only in the lobby.
+	SettingsUpdate struct {
+		Settings Settings `json:"settings"`
+	}
+)
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+const MaxPlayerNameLength = 24
+
+var (
+	ErrInvalidCard       = errors.New("invalid card")
+	ErrInvalidPlayerName = errors.New("invalid player name")
+	ErrInvalidPlayerID   = errors.New("invalid player id")
+	ErrMissingToken      = errors.New("missing resume token")
+	ErrInvalidRoomCode   = errors.New("invalid room code")
+
+	ErrInvalidTimeout      = errors.New("invalid timeout")
+	ErrInvalidTableSize    = errors.New("invalid table size")
+	ErrInvalidEndCondition = errors.New("invalid end condition")
+	ErrInvalidRuleset      = errors.New("invalid ruleset")
+	ErrNotEnoughCards      = errors.New("not enough cards for the table size")
+)
+
+func (c Card) Validate() error {
+	if c.ID < 1 || c.ID > 4 || c.Type < 1 || c.Type > 4 {
+		return ErrInvalidCard
+	}
+
+	return nil
+}
+
+func (s SetName) Validate() error {
+	if s.Name == "" || utf8.RuneCountInString(s.Name) > MaxPlayerNameLength {
+		return ErrInvalidPlayerName
+	}
+
+	if len(strings.ToUpper(strings.TrimSpace(s.RoomCode))) > RoomCodeLength {
+		return ErrInvalidRoomCode
+	}
+
+	return nil
+}
+
+func (r Resume) Validate() error {
+	if r.ResumeToken == "" {
+		return ErrMissingToken
+	}
+
+	return nil
+}
+
+func (b BidSelected) Validate() error {
+	if b.IsRoundDone {
+		return nil
+	}
+
+	return b.Card.Validate()
+}
+
+func (o OfferSelected) Validate() error {
+	return o.Card.Validate()
+}
+
+func (p PlayerChooseOffer) Validate() error {
+	if p.PlayerID < 1 {
+		return ErrInvalidPlayerID
+	}
+
+	return nil
+}
+
+func (s Settings) Validate() error {
+	t := s.Timeouts
+	timeouts := []int{
+		t.ChooseBid, t.ShowBid, t.ChooseOffer, t.ShowOffer, t.BetweenActions, t.OffersFinished,
+		t.ShowSelectedOffer, t.PrepareForNextTurn, t.EndOfRound, t.UpdateScore, t.SumScore,
+	}
+	for _, timeout := range timeouts {
+		if timeout < MinTimeoutMilliseconds || timeout > MaxTimeoutMilliseconds {
+			return ErrInvalidTimeout
+		}
+	}
+
+	if s.TableSize < MinTableSize || s.TableSize > MaxTableSize {
+		return ErrInvalidTableSize
+	}
+
+	if s.EndCondition.Rounds < 0 || s.EndCondition.TargetPoints < 0 {
+		return ErrInvalidEndCondition
+	}
+
+	if !s.Ruleset.IsValid() {
+		return ErrInvalidRuleset
+	}
+
+	if s.TableSize*s.Ruleset.HandSize() > s.Ruleset.DeckSize() {
+		return ErrNotEnoughCards
+	}
+
+	return nil
+}
+
+func (u SettingsUpdate) Validate() error {
+	return u.Settings.Validate()
+}
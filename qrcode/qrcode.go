@@ -0,0 +1,57 @@
+// Package qrcode renders the join URL as a QR code, for the terminal the
+// server runs in and as a PNG for the hub.
+package qrcode
+
+import (
+	"strings"
+
+	"rsc.io/qr"
+)
+
+// quietZone is the blank border, in modules, scanners need around the code.
+const quietZone = 2
+
+// pngScale is the size in pixels of a module of the PNG.
+const pngScale = 8
+
+// PNG encodes text as a QR code image.
+func PNG(text string) ([]byte, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return nil, err
+	}
+	code.Scale = pngScale
+
+	return code.PNG(), nil
+}
+
+// Terminal draws text as a QR code with block characters, two modules per
+// line. It is drawn light on dark, for terminals with a dark background.
+func Terminal(text string) (string, error) {
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		return "", err
+	}
+
+	light := func(x, y int) bool { return !code.Black(x, y) }
+
+	var b strings.Builder
+	for y := -quietZone; y < code.Size+quietZone; y += 2 {
+		for x := -quietZone; x < code.Size+quietZone; x++ {
+			top, bottom := light(x, y), light(x, y+1) && y+1 < code.Size+quietZone
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteByte('\n')
+	}
+
+	return b.String(), nil
+}
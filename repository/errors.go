@@ -5,4 +5,6 @@ import "errors"
 var (
 	ErrPlayerCountTooHigh  = errors.New("player count too high")
 	ErrPlayerAlreadyExists = errors.New("player already exists")
+	ErrUnknownResumeToken  = errors.New("unknown resume token")
+	ErrCardNotInHand       = errors.New("card not in the player's hand")
 )
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/georgysavva/scany/sqlscan"
+)
+
+func (r *Repository) SaveGameState(ctx context.Context, state models.GameState) error {
+	r.log.DebugContext(ctx, "saving game state", "phase", state.Phase, "resume", state.Resume)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		r.log.ErrorContext(ctx, "failed to marshal game state", "error", err)
+		return err
+	}
+
+	const query = `--sql
+		INSERT INTO game_state (id, state) VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET state = excluded.state, updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := r.db.ExecContext(ctx, query, string(data)); err != nil {
+		r.log.ErrorContext(ctx, "failed to save game state", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// GetGameState returns the state of the game that was running, if any.
+func (r *Repository) GetGameState(ctx context.Context) (models.GameState, bool, error) {
+	r.log.DebugContext(ctx, "getting game state")
+
+	const query = `--sql
+		SELECT state FROM game_state WHERE id = 1
+	`
+	var data string
+	if err := sqlscan.Get(ctx, r.db, &data, query); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.GameState{}, false, nil
+		}
+		r.log.ErrorContext(ctx, "failed to get game state", "error", err)
+		return models.GameState{}, false, err
+	}
+
+	var state models.GameState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		r.log.ErrorContext(ctx, "failed to unmarshal game state", "error", err)
+		return models.GameState{}, false, err
+	}
+
+	return state, true, nil
+}
+
+func (r *Repository) ClearGameState(ctx context.Context) error {
+	r.log.DebugContext(ctx, "clearing game state")
+
+	const query = `--sql
+		DELETE FROM game_state
+	`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		r.log.ErrorContext(ctx, "failed to clear game state", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// DeactivatePlayers marks every player as gone, until they reconnect.
+func (r *Repository) DeactivatePlayers(ctx context.Context) error {
+	r.log.DebugContext(ctx, "deactivating all players")
+
+	const query = `--sql
+		UPDATE players SET is_active = FALSE
+	`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		r.log.ErrorContext(ctx, "failed to deactivate players", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) SetResumeToken(ctx context.Context, playerID int, token string) error {
+	r.log.DebugContext(ctx, "setting resume token", "player_id", playerID)
+
+	const query = `--sql
+		UPDATE players SET resume_token = ? WHERE player_id = ?
+	`
+	if _, err := r.db.ExecContext(ctx, query, token, playerID); err != nil {
+		r.log.ErrorContext(ctx, "failed to set resume token", "error", err, "player_id", playerID)
+		return err
+	}
+
+	return nil
+}
+
+// RevokeResumeToken makes the player's resume token worthless, so they can't
+// get their seat back with it.
+func (r *Repository) RevokeResumeToken(ctx context.Context, playerID int) error {
+	r.log.DebugContext(ctx, "revoking resume token", "player_id", playerID)
+
+	const query = `--sql
+		UPDATE players SET resume_token = NULL WHERE player_id = ?
+	`
+	if _, err := r.db.ExecContext(ctx, query, playerID); err != nil {
+		r.log.ErrorContext(ctx, "failed to revoke resume token", "error", err, "player_id", playerID)
+		return err
+	}
+
+	return nil
+}
+
+// ResumePlayer marks the player holding token as active again.
+func (r *Repository) ResumePlayer(ctx context.Context, token string) (models.Player, error) {
+	r.log.DebugContext(ctx, "resuming player")
+
+	const query = `--sql
+		UPDATE players SET is_active = TRUE
+		WHERE resume_token = ?
+		RETURNING player_id, player_name
+	`
+	var player models.Player
+	if err := sqlscan.Get(ctx, r.db, &player, query, token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Player{}, ErrUnknownResumeToken
+		}
+		r.log.ErrorContext(ctx, "failed to resume player", "error", err)
+		return models.Player{}, err
+	}
+
+	return player, nil
+}
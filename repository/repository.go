@@ -3,10 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log/slog"
 	"strings"
 
-	"github.com/Jubris-Knifes/wgj25-back/config"
 	"github.com/Jubris-Knifes/wgj25-back/models"
 	"github.com/georgysavva/scany/sqlscan"
 )
@@ -44,7 +44,10 @@ func (r *Repository) SwapCardHolders(ctx context.Context,
 		WHERE player_id = ? AND card_id = ? AND card_type = ? AND is_real = ?
 	`
 
-	_, err = tx.ExecContext(ctx, queryUpdateCard, player2, player1, card1.ID, card1.Type, card1.IsReal)
+	result, err := tx.ExecContext(ctx, queryUpdateCard, player2, player1, card1.ID, card1.Type, card1.IsReal)
+	if err == nil {
+		err = cardMoved(result)
+	}
 	if err != nil {
 		r.log.ErrorContext(ctx, "give player one's card to player 2",
 			"card", card1, "player_one", player1, "player_two", player2,
@@ -52,7 +55,10 @@ func (r *Repository) SwapCardHolders(ctx context.Context,
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx, queryUpdateCard, player1, player2, card2.ID, card2.Type, card2.IsReal)
+	result, err = tx.ExecContext(ctx, queryUpdateCard, player1, player2, card2.ID, card2.Type, card2.IsReal)
+	if err == nil {
+		err = cardMoved(result)
+	}
 	if err != nil {
 		r.log.ErrorContext(ctx, "give player two's card to player 1",
 			"card", card2, "player_one", player1, "player_two", player2,
@@ -68,6 +74,20 @@ func (r *Repository) SwapCardHolders(ctx context.Context,
 	return nil
 }
 
+// cardMoved fails when an update moved no card, the player didn't hold it.
+func cardMoved(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrCardNotInHand
+	}
+
+	return nil
+}
+
 func (r *Repository) GetPlayerScores(ctx context.Context) ([]models.Score, error) {
 	r.log.DebugContext(ctx, "getting player scores")
 
@@ -85,7 +105,88 @@ func (r *Repository) GetPlayerScores(ctx context.Context) ([]models.Score, error
 	return scores, nil
 }
 
-func (r *Repository) NewPlayer(ctx context.Context, playerName string) (int, error) {
+// GetPlayerScoresOf returns the scores of the given players, whether they
+// are still at the table or not, in the order they were asked for.
+func (r *Repository) GetPlayerScoresOf(ctx context.Context, playerIDs []int) ([]models.Score, error) {
+	r.log.DebugContext(ctx, "getting player scores", "player_ids", playerIDs)
+
+	if len(playerIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT player_id, points FROM player_scores
+		WHERE player_id IN (` + strings.TrimSuffix(strings.Repeat("?,", len(playerIDs)), ",") + `)
+	`
+	args := make([]any, 0, len(playerIDs))
+	for _, playerID := range playerIDs {
+		args = append(args, playerID)
+	}
+
+	var found []models.Score
+	if err := sqlscan.Select(ctx, r.db, &found, query, args...); err != nil {
+		r.log.ErrorContext(ctx, "failed to get player scores", "error", err)
+		return nil, err
+	}
+
+	scores := make([]models.Score, 0, len(playerIDs))
+	for _, playerID := range playerIDs {
+		for _, score := range found {
+			if score.PlayerID == playerID {
+				scores = append(scores, score)
+			}
+		}
+	}
+
+	return scores, nil
+}
+
+func (r *Repository) SetPlayerScores(ctx context.Context, scores []models.Score) error {
+	r.log.DebugContext(ctx, "setting player scores", "scores", scores)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	defer rollback(tx)
+	if err != nil {
+		r.log.ErrorContext(ctx, "failed to begin transaction", "error", err)
+		return err
+	}
+
+	const query = `--sql
+		UPDATE player_scores SET points = ? WHERE player_id = ?
+	`
+	for _, score := range scores {
+		if _, err := tx.ExecContext(ctx, query, score.Points, score.PlayerID); err != nil {
+			r.log.ErrorContext(ctx, "failed to update player score", "error", err, "player_id", score.PlayerID)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.log.ErrorContext(ctx, "failed to commit transaction", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) ResetScores(ctx context.Context) error {
+	r.log.DebugContext(ctx, "resetting player scores")
+
+	const query = `--sql
+		UPDATE player_scores SET points = 0
+	`
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		r.log.ErrorContext(ctx, "failed to reset player scores", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// NewPlayer adds a player to the table, unless maxPlayers are already at it.
+// Names stay with their player, who can only get back to their seat with
+// their resume token.
+func (r *Repository) NewPlayer(ctx context.Context, playerName string, maxPlayers int) (int, error) {
 	r.log.DebugContext(ctx, "creating new player", "player_name", playerName)
 
 	tx, err := r.db.BeginTx(ctx, nil)
@@ -96,31 +197,43 @@ func (r *Repository) NewPlayer(ctx context.Context, playerName string) (int, err
 	}
 	const countQuery = `
 		SELECT COUNT(*) from players 
-		WHERE is_active = TRUE AND player_name <> ?
+		WHERE is_active = TRUE
 	`
 	var count int
-	if err := sqlscan.Get(ctx, tx, &count, countQuery, playerName); err != nil {
+	if err := sqlscan.Get(ctx, tx, &count, countQuery); err != nil {
 		r.log.ErrorContext(ctx, "failed to count players", "error", err)
 		return 0, err
 	}
 
-	if count >= config.Get().MaxPlayers {
-		r.log.WarnContext(ctx, "player count too high", "count", count, "max", config.Get().MaxPlayers)
+	if count >= maxPlayers {
+		r.log.WarnContext(ctx, "player count too high", "count", count, "max", maxPlayers)
 		return 0, ErrPlayerCountTooHigh
 	}
 
 	const insertQuery = `
 		INSERT INTO players (player_name)
 		VALUES (?) 
-		ON CONFLICT(player_name) DO UPDATE SET is_active = TRUE
+		ON CONFLICT(player_name) DO NOTHING
 		RETURNING player_id
 	`
 	var playerID int
 	if err := sqlscan.Get(ctx, tx, &playerID, insertQuery, playerName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrPlayerAlreadyExists
+		}
 		r.log.ErrorContext(ctx, "failed to insert player", "error", err)
 		return 0, err
 	}
 
+	const insertScoreQuery = `--sql
+		INSERT INTO player_scores (player_id) VALUES (?)
+		ON CONFLICT(player_id) DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, insertScoreQuery, playerID); err != nil {
+		r.log.ErrorContext(ctx, "failed to insert player score", "error", err)
+		return 0, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		r.log.ErrorContext(ctx, "failed to commit transaction", "error", err)
 		return 0, err
@@ -163,6 +276,24 @@ func (r *Repository) GetActivePlayerCount(ctx context.Context) (int, error) {
 	return count, nil
 }
 
+func (r *Repository) GetActivePlayers(ctx context.Context) ([]models.Player, error) {
+	r.log.DebugContext(ctx, "getting active players")
+
+	const query = `
+		SELECT player_id, player_name
+		FROM players
+		WHERE is_active = TRUE
+		ORDER BY player_id
+	`
+	var players []models.Player
+	if err := sqlscan.Select(ctx, r.db, &players, query); err != nil {
+		r.log.ErrorContext(ctx, "failed to get active players", "error", err)
+		return nil, err
+	}
+
+	return players, nil
+}
+
 func (r *Repository) GetActivePlayerIDs(ctx context.Context) ([]int, error) {
 	r.log.DebugContext(ctx, "getting active player IDs")
 
@@ -185,7 +316,7 @@ func (r *Repository) GetActivePlayerIDs(ctx context.Context) ([]int, error) {
 
 func (r *Repository) DropPlayerHands(ctx context.Context) error {
 	const query = `--sql
-		DELETE FROM player_hands
+		DELETE FROM player_hand
 	`
 
 	_, err := r.db.ExecContext(ctx, query)
@@ -232,7 +363,7 @@ func (r *Repository) GetPlayerHand(ctx context.Context, playerID int) ([]models.
 	r.log.DebugContext(ctx, "getting player hand", "player_id", playerID)
 
 	const query = `
-		SELECT card_id, card_type, is_real
+		SELECT card_id AS id, card_type AS type, is_real
 		FROM player_hand
 		WHERE player_id = ?
 	`
@@ -254,6 +385,12 @@ func (r *Repository) GetCurrentPlayerID(ctx context.Context) (int, error) {
 	`
 	var playerID int
 	if err := sqlscan.Get(ctx, r.db, &playerID, query); err != nil {
+		// Outside of a round there is no current player, callers check for
+		// sql.ErrNoRows.
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.DebugContext(ctx, "no current player")
+			return 0, err
+		}
 		r.log.ErrorContext(ctx, "failed to get current player ID", "error", err)
 		return 0, err
 	}
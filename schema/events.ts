@@ -0,0 +1,706 @@
+// Code generated by eventgen from the models package. DO NOT EDIT.
+
+export const ProtocolVersion = 1;
+export const MinProtocolVersion = 1;
+export const RoomCodeLength = 4;
+export const ClassicFakeCards = 4;
+export const MinTableSize = 2;
+export const MaxTableSize = 4;
+export const MinTimeoutMilliseconds = 250;
+export const MaxTimeoutMilliseconds = 120000;
+export const MaxPlayerNameLength = 24;
+
+export type AutoActionKind =
+  | "bid"
+  | "end_round"
+  | "offer"
+  | "choose_offer";
+
+export type AutoActionReason =
+  | "timeout"
+  | "afk";
+
+export type ConnectionQualityLevel =
+  | "good"
+  | "fair"
+  | "poor";
+
+export type ErrorCode =
+  | "invalid_json"
+  | "invalid_encoding"
+  | "unknown_event"
+  | "invalid_payload"
+  | "not_allowed"
+  | "wrong_phase"
+  | "rate_limited"
+  | "duplicate_action"
+  | "player_count_too_high"
+  | "invalid_player_count"
+  | "players_not_ready"
+  | "game_already_running"
+  | "unknown_room"
+  | "name_taken"
+  | "game_not_running"
+  | "game_already_paused"
+  | "game_not_paused"
+  | "internal_error";
+
+export type EventType =
+  | "end_of_round"
+  | "update_score"
+  | "sum_score"
+  | "prepare_for_next_turn"
+  | "choose_Offer"
+  | "Offer_selected"
+  | "made_offer"
+  | "offers_finished"
+  | "select_offer_choices"
+  | "select_offer_chosen"
+  | "player_choose_offer"
+  | "choose_bid"
+  | "show_back_of_card_bid"
+  | "bid_selected"
+  | "cards_update"
+  | "dealing_cards"
+  | "cards_dealt"
+  | "set_name_request"
+  | "set_name_response"
+  | "resume"
+  | "player_joined"
+  | "lobby_roster"
+  | "set_ready"
+  | "start_game"
+  | "game_ended"
+  | "state_snapshot"
+  | "player_afk"
+  | "auto_action"
+  | "game_error"
+  | "pause_game"
+  | "resume_game"
+  | "game_paused"
+  | "game_resumed"
+  | "ping"
+  | "pong"
+  | "connection_quality"
+  | "hello"
+  | "hello_response"
+  | "error"
+  | "ack"
+  | "create_room"
+  | "room"
+  | "settings"
+  | "settings_update";
+
+export type Phase =
+  | "lobby"
+  | "dealing"
+  | "bid"
+  | "show_bid"
+  | "offers"
+  | "show_offers"
+  | "choose_offer"
+  | "show_chosen_offer"
+  | "next_turn"
+  | "end_of_round";
+
+export type Role =
+  | "player"
+  | "hub"
+  | "spectator";
+
+export type Ruleset =
+  | "classic"
+  | "no_fakes";
+
+export interface Ack {
+  request_id: string;
+}
+
+export interface AutoAction {
+  player_id: number;
+  action: AutoActionKind;
+  reason: AutoActionReason;
+  card?: Card | null;
+  offer_player_id?: number;
+}
+
+export interface BidSelected {
+  card: Card;
+  is_round_over: boolean;
+}
+
+export interface Card {
+  id: number;
+  type: number;
+  is_real: boolean;
+}
+
+export interface CardFace {
+  id: number;
+  type: number;
+}
+
+export interface CardsDealt {
+  cards: Card[];
+}
+
+export interface CardsUpdate {
+  cards: Card[];
+}
+
+export interface ChooseBid {
+  player_id: number;
+  timeout: number;
+  deadline: number;
+  can_finish_round: boolean;
+}
+
+export interface ChooseOffer {
+  player_ids: number[];
+  timeout: number;
+  deadline: number;
+}
+
+export interface ConnectionQuality {
+  players: PlayerConnection[];
+}
+
+export type CreateRoom = Record<string, never>;
+
+export type DealingCards = Record<string, never>;
+
+export interface EndCondition {
+  rounds: number;
+  target_points: number;
+}
+
+export interface EndOfRound {
+  timeout: number;
+  deadline: number;
+}
+
+export interface ErrorDetails {
+  code: ErrorCode;
+  message: string;
+  request_id?: string;
+}
+
+export interface GameEnded {
+  reason: string;
+}
+
+export interface GameError {
+  step: string;
+  retrying: boolean;
+}
+
+export interface GamePaused {
+  remaining_timeout: number;
+}
+
+export interface GameResumed {
+  timeout: number;
+  deadline: number;
+}
+
+export interface HandScore {
+  player_id: number;
+  points: number;
+  cards: CardFace[];
+}
+
+export interface Hello {
+  protocol_version: number;
+  role: Role;
+  encodings?: string[];
+}
+
+export interface HelloResponse {
+  accepted: boolean;
+  protocol_version: number;
+  role: Role;
+  encoding: string;
+  reason?: string;
+}
+
+export interface LobbyRoster {
+  host_player_id?: number;
+  players: Player[];
+}
+
+export interface MadeOffer {
+  player_ids: number[];
+}
+
+export interface OfferFace {
+  player_id: number;
+  card: CardFace;
+}
+
+export interface OfferSelected {
+  card: Card;
+}
+
+export interface OffersFinished {
+  offers: OfferFace[];
+  timeout: number;
+  deadline: number;
+}
+
+export type PauseGame = Record<string, never>;
+
+export interface Ping {
+  client_time: number;
+}
+
+export interface Player {
+  player_id: number;
+  name: string;
+  connected: boolean;
+  ready: boolean;
+}
+
+export interface PlayerAFK {
+  player_id: number;
+  afk: boolean;
+}
+
+export interface PlayerChooseOffer {
+  player_id: number;
+}
+
+export interface PlayerConnection {
+  player_id: number;
+  quality: ConnectionQualityLevel;
+  average_milliseconds: number;
+  jitter_milliseconds: number;
+}
+
+export interface PlayerJoined {
+  player_id: number;
+  name: string;
+}
+
+export interface PlayerOffer {
+  player_id: number;
+  card: Card;
+}
+
+export interface PointSettings {
+  fake_poker: number;
+  poker: number;
+  one_of_each: number;
+  full_house: number;
+  three_of_a_kind: number;
+  two_pair: number;
+  pair: number;
+  fake_one: number;
+  fake_two: number;
+  fake_three: number;
+}
+
+export interface Pong {
+  client_time: number;
+  server_time: number;
+}
+
+export interface PrepareForNextTurn {
+  timeout: number;
+  deadline: number;
+  next_bidder: number;
+}
+
+export interface Resume {
+  resume_token: string;
+}
+
+export type ResumeGame = Record<string, never>;
+
+export interface Room {
+  code: string;
+  join_url?: string;
+}
+
+export interface Score {
+  player_id: number;
+  points: number;
+}
+
+export interface ScoreChange {
+  player_id: number;
+  old_score: number;
+  new_score: number;
+}
+
+export interface SelectOfferChoices {
+  offers: OfferFace[];
+  timeout: number;
+  deadline: number;
+}
+
+export interface SelectOfferChosen {
+  timeout: number;
+  deadline: number;
+  player_id: number;
+}
+
+export interface SetName {
+  name: string;
+  room_code?: string;
+}
+
+export interface SetNameResponse {
+  assigned_player_id: number;
+  resume_token?: string;
+}
+
+export interface SetReady {
+  ready: boolean;
+}
+
+export interface Settings {
+  timeouts: TimeoutSettings;
+  points: PointSettings;
+  end_condition: EndCondition;
+  table_size: number;
+  ruleset: Ruleset;
+}
+
+export interface SettingsUpdate {
+  settings: Settings;
+}
+
+export interface ShowBackOfCardBid {
+  timeout: number;
+  deadline: number;
+}
+
+export interface ShowBidSelected {
+  card: Card;
+  timeout: number;
+  deadline: number;
+}
+
+export type StartGame = Record<string, never>;
+
+export interface StateSnapshot {
+  phase: Phase;
+  paused: boolean;
+  timeout?: number;
+  deadline?: number;
+  roster: Player[];
+  scores: Score[];
+  current_player_id?: number | null;
+  hand?: Card[];
+  bid_placed: boolean;
+  bid?: Card | null;
+  players_offered?: number[];
+  offers?: OfferFace[];
+  own_offer?: PlayerOffer | null;
+  afk_player_ids?: number[];
+  host_player_id?: number;
+  settings: Settings;
+  room?: Room | null;
+}
+
+export interface SumScore {
+  timeout: number;
+  deadline: number;
+  scores: ScoreChange[];
+}
+
+export interface TimeoutSettings {
+  choose_bid: number;
+  show_bid: number;
+  choose_offer: number;
+  show_offer: number;
+  between_actions: number;
+  offers_finished: number;
+  show_selected_offer: number;
+  prepare_for_next_turn: number;
+  end_of_round: number;
+  update_score: number;
+  sum_score: number;
+}
+
+export interface UpdateScore {
+  timeout: number;
+  deadline: number;
+  scores: HandScore[];
+}
+
+export interface EndOfRoundEvent {
+  type: "end_of_round";
+  event_data: EndOfRound;
+}
+
+export interface UpdateScoreEvent {
+  type: "update_score";
+  event_data: UpdateScore;
+}
+
+export interface SumScoreEvent {
+  type: "sum_score";
+  event_data: SumScore;
+}
+
+export interface PrepareForNextTurnEvent {
+  type: "prepare_for_next_turn";
+  event_data: PrepareForNextTurn;
+}
+
+export interface PlayerChooseOfferEvent {
+  type: "player_choose_offer";
+  event_data: PlayerChooseOffer;
+}
+
+export interface SelectOfferChosenEvent {
+  type: "select_offer_chosen";
+  event_data: SelectOfferChosen;
+}
+
+export interface SelectOfferChoicesEvent {
+  type: "select_offer_choices";
+  event_data: SelectOfferChoices;
+}
+
+export interface OffersFinishedEvent {
+  type: "offers_finished";
+  event_data: OffersFinished;
+}
+
+export interface MadeOfferEvent {
+  type: "made_offer";
+  event_data: MadeOffer;
+}
+
+export interface ChooseOfferEvent {
+  type: "choose_Offer";
+  event_data: ChooseOffer;
+}
+
+export interface OfferSelectedEvent {
+  type: "Offer_selected";
+  event_data: OfferSelected;
+}
+
+export interface ShowBackOfCardBidEvent {
+  type: "show_back_of_card_bid";
+  event_data: ShowBackOfCardBid;
+}
+
+export interface ChooseBidEvent {
+  type: "choose_bid";
+  event_data: ChooseBid;
+}
+
+export interface BidSelectedEvent {
+  type: "bid_selected";
+  event_data: BidSelected;
+}
+
+export interface ShowBidSelectedEvent {
+  type: EventType;
+  event_data: ShowBidSelected;
+}
+
+export interface CardsUpdateEvent {
+  type: "cards_update";
+  event_data: CardsUpdate;
+}
+
+export interface DealingCardsEvent {
+  type: "dealing_cards";
+  event_data: DealingCards;
+}
+
+export interface CardsDealtEvent {
+  type: "cards_dealt";
+  event_data: CardsDealt;
+}
+
+export interface SetNameEvent {
+  type: "set_name_request";
+  event_data: SetName;
+}
+
+export interface SetNameResponseEvent {
+  type: "set_name_response";
+  event_data: SetNameResponse;
+}
+
+export interface ResumeEvent {
+  type: "resume";
+  event_data: Resume;
+}
+
+export interface PlayerJoinedEvent {
+  type: "player_joined";
+  event_data: PlayerJoined;
+}
+
+export interface LobbyRosterEvent {
+  type: "lobby_roster";
+  event_data: LobbyRoster;
+}
+
+export interface SetReadyEvent {
+  type: "set_ready";
+  event_data: SetReady;
+}
+
+export interface StartGameEvent {
+  type: "start_game";
+  event_data: StartGame;
+}
+
+export interface GameEndedEvent {
+  type: "game_ended";
+  event_data: GameEnded;
+}
+
+export interface StateSnapshotEvent {
+  type: "state_snapshot";
+  event_data: StateSnapshot;
+}
+
+export interface PlayerAFKEvent {
+  type: "player_afk";
+  event_data: PlayerAFK;
+}
+
+export interface AutoActionEvent {
+  type: "auto_action";
+  event_data: AutoAction;
+}
+
+export interface GameErrorEvent {
+  type: "game_error";
+  event_data: GameError;
+}
+
+export interface PauseGameEvent {
+  type: "pause_game";
+  event_data: PauseGame;
+}
+
+export interface ResumeGameEvent {
+  type: "resume_game";
+  event_data: ResumeGame;
+}
+
+export interface GamePausedEvent {
+  type: "game_paused";
+  event_data: GamePaused;
+}
+
+export interface GameResumedEvent {
+  type: "game_resumed";
+  event_data: GameResumed;
+}
+
+export interface PingEvent {
+  type: "ping";
+  event_data: Ping;
+}
+
+export interface PongEvent {
+  type: "pong";
+  event_data: Pong;
+}
+
+export interface ConnectionQualityEvent {
+  type: "connection_quality";
+  event_data: ConnectionQuality;
+}
+
+export interface HelloEvent {
+  type: "hello";
+  event_data: Hello;
+}
+
+export interface HelloResponseEvent {
+  type: "hello_response";
+  event_data: HelloResponse;
+}
+
+export interface ErrorEvent {
+  type: "error";
+  event_data: ErrorDetails;
+}
+
+export interface AckEvent {
+  type: "ack";
+  event_data: Ack;
+}
+
+export interface CreateRoomEvent {
+  type: "create_room";
+  event_data: CreateRoom;
+}
+
+export interface RoomEvent {
+  type: "room";
+  event_data: Room;
+}
+
+export interface SettingsEvent {
+  type: "settings";
+  event_data: Settings;
+}
+
+export interface SettingsUpdateEvent {
+  type: "settings_update";
+  event_data: SettingsUpdate;
+}
+
+export type Event =
+  | EndOfRoundEvent
+  | UpdateScoreEvent
+  | SumScoreEvent
+  | PrepareForNextTurnEvent
+  | PlayerChooseOfferEvent
+  | SelectOfferChosenEvent
+  | SelectOfferChoicesEvent
+  | OffersFinishedEvent
+  | MadeOfferEvent
+  | ChooseOfferEvent
+  | OfferSelectedEvent
+  | ShowBackOfCardBidEvent
+  | ChooseBidEvent
+  | BidSelectedEvent
+  | ShowBidSelectedEvent
+  | CardsUpdateEvent
+  | DealingCardsEvent
+  | CardsDealtEvent
+  | SetNameEvent
+  | SetNameResponseEvent
+  | ResumeEvent
+  | PlayerJoinedEvent
+  | LobbyRosterEvent
+  | SetReadyEvent
+  | StartGameEvent
+  | GameEndedEvent
+  | StateSnapshotEvent
+  | PlayerAFKEvent
+  | AutoActionEvent
+  | GameErrorEvent
+  | PauseGameEvent
+  | ResumeGameEvent
+  | GamePausedEvent
+  | GameResumedEvent
+  | PingEvent
+  | PongEvent
+  | ConnectionQualityEvent
+  | HelloEvent
+  | HelloResponseEvent
+  | ErrorEvent
+  | AckEvent
+  | CreateRoomEvent
+  | RoomEvent
+  | SettingsEvent
+  | SettingsUpdateEvent;
+
+// Events sent by clients may carry a request_id, echoed back in the ack or
+// error event that answers them.
+export type WithRequestID<E extends Event> = E & { request_id?: string };
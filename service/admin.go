@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/Jubris-Knifes/wgj25-back/transport"
+	"github.com/olahol/melody"
+)
+
+func (s *service) Sessions(ctx context.Context) ([]models.SessionInfo, error) {
+	sessions := s.sessions.All()
+
+	infos := make([]models.SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		info := models.SessionInfo{
+			RemoteAddress: session.RemoteAddr().String(),
+			Role:          sessionRole(session),
+			QueueLength:   transport.QueueLength(session),
+		}
+		if playerID, ok := transport.SessionPlayerID(session); ok {
+			info.PlayerID = &playerID
+		}
+		if stats, ok := transport.Latency(session); ok {
+			info.Latency = &stats
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// KickPlayer takes a player off the table, closing their sessions if they
+// have any left. Their resume token is revoked, so they can't take their seat
+// back.
+func (s *service) KickPlayer(ctx context.Context, playerID int) error {
+	s.log.InfoContext(ctx, "kicking player", "player_id", playerID)
+
+	active, err := s.repo.GetActivePlayerIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	sessions := s.sessions.Player(playerID)
+	if len(sessions) == 0 && !slices.Contains(active, playerID) {
+		return ErrPlayerNotAtTable
+	}
+
+	for _, session := range sessions {
+		msg := melody.FormatCloseMessage(closeCodeKicked, "kicked by admin")
+		if err := session.CloseWithMsg(msg); err != nil {
+			s.log.ErrorContext(ctx, "failed to close player session", "error", err, "player_id", playerID)
+			return err
+		}
+	}
+
+	if err := s.repo.RevokeResumeToken(ctx, playerID); err != nil {
+		return err
+	}
+
+	if err := s.repo.ClosePlayer(ctx, playerID); err != nil {
+		return err
+	}
+
+	s.playerLeft(ctx, playerID)
+
+	return nil
+}
+
+// ForceStartRound deals the current round of the running game again, keeping
+// its seats and the rounds already played. Without a game it starts a new one
+// with the players currently at the table.
+func (s *service) ForceStartRound(ctx context.Context) error {
+	if s.isGameRunning() {
+		if !s.restartRound() {
+			return ErrGameNotRunning
+		}
+
+		s.log.InfoContext(ctx, "restarting current round")
+
+		return nil
+	}
+
+	count, err := s.repo.GetActivePlayerCount(ctx)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get active player count", "error", err)
+		return err
+	}
+
+	if count < minPlayersPerGame || count > s.rules().TableSize {
+		s.log.WarnContext(ctx, "cannot force start round", "count", count)
+		return ErrInvalidPlayerCount
+	}
+
+	if !s.startGame() {
+		return ErrGameAlreadyRunning
+	}
+
+	return nil
+}
+
+func (s *service) SkipPhase(ctx context.Context) error {
+	if !s.isGameRunning() {
+		return ErrGameNotRunning
+	}
+
+	if !s.clock.skip() {
+		return ErrNoTimedPhase
+	}
+
+	s.log.InfoContext(ctx, "skipping current phase")
+
+	return nil
+}
+
+func (s *service) ResetScores(ctx context.Context) error {
+	return s.repo.ResetScores(ctx)
+}
+
+// EndGame ends the running game, or drops the restored one still waiting for
+// its players along with its saved state.
+func (s *service) EndGame(ctx context.Context) error {
+	if !s.hasGame() {
+		return ErrGameNotRunning
+	}
+
+	s.endGame("ended by admin")
+
+	return nil
+}
+
+func (s *service) PauseGame(ctx context.Context) error {
+	return s.pauseGame(ctx)
+}
+
+func (s *service) ResumeGame(ctx context.Context) error {
+	return s.resumeGame(ctx)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/Jubris-Knifes/wgj25-back/config"
+	"github.com/Jubris-Knifes/wgj25-back/models"
+)
+
+// afkTracker counts how many times in a row each player let a phase time
+// out. Players over the limit are away from keyboard, and the bot plays for
+// them until they send something again.
+type afkTracker struct {
+	mu       sync.Mutex
+	timeouts map[int]int
+	away     []int
+}
+
+func newAFKTracker() *afkTracker {
+	return &afkTracker{timeouts: map[int]int{}}
+}
+
+// timedOut records a timeout and reports whether it made the player AFK.
+func (t *afkTracker) timedOut(playerID int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.timeouts[playerID]++
+	if t.timeouts[playerID] < config.Get().AFK.TimeoutLimit || slices.Contains(t.away, playerID) {
+		return false
+	}
+
+	t.away = append(t.away, playerID)
+	return true
+}
+
+// acted resets the timeouts of a player who made it in time.
+func (t *afkTracker) acted(playerID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.timeouts, playerID)
+}
+
+// back gives a player their seat back, and reports whether they were AFK.
+func (t *afkTracker) back(playerID int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.timeouts, playerID)
+	i := slices.Index(t.away, playerID)
+	if i < 0 {
+		return false
+	}
+
+	t.away = slices.Delete(t.away, i, i+1)
+	return true
+}
+
+func (t *afkTracker) isAFK(playerID int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return slices.Contains(t.away, playerID)
+}
+
+// humans returns the players in playerIDs who are not AFK.
+func (t *afkTracker) humans(playerIDs []int) []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return slices.DeleteFunc(slices.Clone(playerIDs), func(id int) bool {
+		return slices.Contains(t.away, id)
+	})
+}
+
+func (t *afkTracker) players() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return slices.Clone(t.away)
+}
+
+func (t *afkTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	clear(t.timeouts)
+	t.away = nil
+}
+
+// playerTimedOut records that playerID let a phase time out, and hands their
+// seat to the bot once they did it too many times in a row.
+func (s *service) playerTimedOut(ctx context.Context, playerID int) {
+	if ctx.Err() != nil || !s.afk.timedOut(playerID) {
+		return
+	}
+
+	s.log.InfoContext(ctx, "player is afk, bot takes over", "player_id", playerID)
+	s.sendPlayerAFK(ctx, playerID, true)
+}
+
+// playerActive gives the seat back to a player who sent something while the
+// bot was playing for them.
+func (s *service) playerActive(ctx context.Context, playerID int) {
+	if !s.afk.back(playerID) {
+		return
+	}
+
+	s.log.InfoContext(ctx, "player is back", "player_id", playerID)
+	s.sendPlayerAFK(ctx, playerID, false)
+}
+
+func (s *service) sendPlayerAFK(ctx context.Context, playerID int, afk bool) {
+	event := models.PlayerAFKEvent{
+		Type: models.EventTypePlayerAFK,
+		EventData: models.PlayerAFK{
+			PlayerID: playerID,
+			AFK:      afk,
+		},
+	}
+
+	if err := s.publish(ctx, toTableAndPlayers([]int{playerID}, event)); err != nil {
+		s.log.ErrorContext(ctx, "failed to send player_afk event", "error", err)
+	}
+}
+
+// sendAutoAction tells a player what the server played for them. The table
+// learns what kind of action it was, but not the card.
+func (s *service) sendAutoAction(ctx context.Context, action models.AutoAction) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	event := models.AutoActionEvent{
+		Type:      models.EventTypeAutoAction,
+		EventData: action,
+	}
+
+	tableCopy := event
+	tableCopy.EventData.Card = nil
+
+	if err := s.publish(ctx, withTableCopy(toPlayer(action.PlayerID, event), tableCopy)); err != nil {
+		s.log.ErrorContext(ctx, "failed to send auto_action event", "error", err)
+	}
+}
+
+func bidAction(playerID int, reason models.AutoActionReason, bid models.Card, endRound bool) models.AutoAction {
+	if endRound {
+		return models.AutoAction{PlayerID: playerID, Action: models.AutoActionEndRound, Reason: reason}
+	}
+
+	return models.AutoAction{PlayerID: playerID, Action: models.AutoActionBid, Reason: reason, Card: &bid}
+}
+
+func offerAction(playerID int, reason models.AutoActionReason, offer models.Card) models.AutoAction {
+	return models.AutoAction{PlayerID: playerID, Action: models.AutoActionOffer, Reason: reason, Card: &offer}
+}
+
+func chooseOfferAction(playerID int, reason models.AutoActionReason, offererID int) models.AutoAction {
+	return models.AutoAction{PlayerID: playerID, Action: models.AutoActionChooseOffer, Reason: reason, OfferPlayerID: offererID}
+}
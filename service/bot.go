@@ -0,0 +1,168 @@
+package service
+
+import (
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/Jubris-Knifes/wgj25-back/config"
+	"github.com/Jubris-Knifes/wgj25-back/models"
+)
+
+// botDelay is how long the bot takes to play, so the table can follow.
+func (s *service) botDelay() time.Duration {
+	return milliseconds(s.rules().Timeouts.BetweenActions)
+}
+
+// strategy picks the actions of a player the server plays for.
+type strategy interface {
+	// bid picks the card to bid, or reports that the round should end.
+	bid(hand []models.Card) (card models.Card, endRound bool)
+	offer(hand []models.Card) models.Card
+	// chooseOffer returns the id of the player whose offer to take for bid.
+	chooseOffer(hand []models.Card, bid models.Card, offers []models.OfferFace) int
+}
+
+// Strategies the server can pick with when a player lets a phase time out.
+const (
+	strategyRandom        = "random"
+	strategyBestHand      = "best_hand"
+	strategyLeastValuable = "least_valuable"
+)
+
+// strategies returns the bot playing for AFK players and the configured
+// strategy picking for players who time out, both scoring hands with points.
+func (s *service) strategies(points models.PointSettings) (bot, autoPick strategy) {
+	name := config.Get().AFK.AutoPickStrategy
+	autoPick, ok := strategyByName(name, points)
+	if !ok {
+		s.log.Warn("unknown auto pick strategy, picking at random", "strategy", name)
+		autoPick = randomPick{}
+	}
+
+	return bestHand{points: points}, autoPick
+}
+
+func strategyByName(name string, points models.PointSettings) (strategy, bool) {
+	switch name {
+	case strategyRandom:
+		return randomPick{}, true
+	case strategyBestHand:
+		return bestHand{points: points}, true
+	case strategyLeastValuable:
+		return leastValuable{}, true
+	}
+
+	return nil, false
+}
+
+// randomPick picks any card, and never ends the round.
+type randomPick struct{}
+
+func (randomPick) bid(hand []models.Card) (models.Card, bool) {
+	return hand[rand.IntN(len(hand))], false
+}
+
+func (randomPick) offer(hand []models.Card) models.Card {
+	return hand[rand.IntN(len(hand))]
+}
+
+func (randomPick) chooseOffer(_ []models.Card, _ models.Card, offers []models.OfferFace) int {
+	return offers[rand.IntN(len(offers))].PlayerID
+}
+
+// leastValuable gets rid of fakes first, then of the kind of card the hand
+// has the fewest of, and takes the offer of the kind it has the most of.
+type leastValuable struct{}
+
+func (l leastValuable) bid(hand []models.Card) (models.Card, bool) {
+	return l.offer(hand), false
+}
+
+func (leastValuable) offer(hand []models.Card) models.Card {
+	kinds := countKinds(hand)
+
+	least := hand[0]
+	for _, card := range hand[1:] {
+		switch {
+		case least.IsReal && !card.IsReal:
+			least = card
+		case least.IsReal == card.IsReal && kinds[card.Type] < kinds[least.Type]:
+			least = card
+		}
+	}
+
+	return least
+}
+
+func (leastValuable) chooseOffer(hand []models.Card, bid models.Card, offers []models.OfferFace) int {
+	kinds := countKinds(replaceCard(hand, bid))
+
+	best := offers[0]
+	for _, offer := range offers[1:] {
+		if kinds[offer.Card.Type] > kinds[best.Card.Type] {
+			best = offer
+		}
+	}
+
+	return best.PlayerID
+}
+
+func countKinds(hand []models.Card) []int {
+	kinds := make([]int, cardKinds+1)
+	for _, card := range hand {
+		kinds[card.Type]++
+	}
+
+	return kinds
+}
+
+// bestHand keeps the hand worth the most points. It only knows what the
+// player it plays for knows, offered cards are taken at face value.
+type bestHand struct {
+	points models.PointSettings
+}
+
+func (b bestHand) bid(hand []models.Card) (models.Card, bool) {
+	if canFinishRound(hand) {
+		return models.Card{}, true
+	}
+
+	return b.cheapestCard(hand), false
+}
+
+func (b bestHand) offer(hand []models.Card) models.Card {
+	return b.cheapestCard(hand)
+}
+
+func (b bestHand) chooseOffer(hand []models.Card, bid models.Card, offers []models.OfferFace) int {
+	best, bestPoints := offers[0].PlayerID, 0
+	for i, offer := range offers {
+		card := models.Card{ID: offer.Card.ID, Type: offer.Card.Type, IsReal: true}
+		points := calculateRoundPoints(b.points, replaceCard(hand, bid, card))
+		if i == 0 || points > bestPoints {
+			best, bestPoints = offer.PlayerID, points
+		}
+	}
+
+	return best
+}
+
+// cheapestCard is the card the rest of the hand is worth the most without.
+func (b bestHand) cheapestCard(hand []models.Card) models.Card {
+	cheapest, bestPoints := hand[0], 0
+	for i, card := range hand {
+		points := calculateRoundPoints(b.points, replaceCard(hand, card))
+		if i == 0 || points > bestPoints {
+			cheapest, bestPoints = card, points
+		}
+	}
+
+	return cheapest
+}
+
+// replaceCard returns hand without card, plus the replacements.
+func replaceCard(hand []models.Card, card models.Card, replacements ...models.Card) []models.Card {
+	result := slices.DeleteFunc(slices.Clone(hand), func(c models.Card) bool { return c == card })
+	return append(result, replacements...)
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// phaseClock keeps track of the deadline of the phase that is currently
+// running, and freezes it while the game is paused.
+type phaseClock struct {
+	mu        sync.Mutex
+	paused    bool
+	deadline  time.Time
+	remaining time.Duration
+	pausedCh  chan struct{}
+	resumedCh chan struct{}
+	// skipCh is closed to skip the phase timer that is waiting, nil while
+	// none is.
+	skipCh chan struct{}
+}
+
+func newPhaseClock() *phaseClock {
+	return &phaseClock{
+		pausedCh:  make(chan struct{}),
+		resumedCh: make(chan struct{}),
+	}
+}
+
+func (c *phaseClock) start(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remaining = d
+	c.deadline = time.Now().Add(d)
+}
+
+// state returns the time left on the current phase. While running, pausedCh
+// gets closed when the game is paused; while paused, resumedCh gets closed when
+// the game is resumed.
+func (c *phaseClock) state() (paused bool, remaining time.Duration, pausedCh, resumedCh <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		return true, c.remaining, nil, c.resumedCh
+	}
+
+	return false, time.Until(c.deadline), c.pausedCh, nil
+}
+
+func (c *phaseClock) pause() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		return 0, false
+	}
+
+	c.paused = true
+	c.remaining = max(time.Until(c.deadline), 0)
+	close(c.pausedCh)
+	c.resumedCh = make(chan struct{})
+
+	return c.remaining, true
+}
+
+func (c *phaseClock) resume() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		return 0, false
+	}
+
+	c.paused = false
+	c.deadline = time.Now().Add(c.remaining)
+	close(c.resumedCh)
+	c.pausedCh = make(chan struct{})
+
+	return c.remaining, true
+}
+
+// armSkip makes the timer starting now the one a skip ends.
+func (c *phaseClock) armSkip() chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.skipCh = make(chan struct{})
+
+	return c.skipCh
+}
+
+// disarmSkip is called once the timer armed with skipCh is over.
+func (c *phaseClock) disarmSkip(skipCh chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.skipCh == skipCh {
+		c.skipCh = nil
+	}
+}
+
+// skip ends the phase timer that is waiting. It reports false if there is
+// none, so a skip can't carry over to a later phase.
+func (c *phaseClock) skip() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.skipCh == nil {
+		return false
+	}
+
+	close(c.skipCh)
+	c.skipCh = nil
+
+	return true
+}
+
+// timeLeft returns the time left on the phase timer that is waiting, if any.
+func (c *phaseClock) timeLeft() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.skipCh == nil {
+		return 0, false
+	}
+
+	if c.paused {
+		return c.remaining, true
+	}
+
+	return max(time.Until(c.deadline), 0), true
+}
+
+func (c *phaseClock) reset() {
+	if _, ok := c.resume(); ok {
+		c.start(0)
+	}
+}
+
+// deadlineIn is the deadline sent to clients for a phase ending in d.
+func deadlineIn(d time.Duration) int64 {
+	return time.Now().Add(d).UnixMilli()
+}
+
+type phaseTimer struct {
+	done <-chan struct{}
+	stop context.CancelFunc
+}
+
+// startPhaseTimer starts the timeout of the current phase. done is closed once
+// d has elapsed, not counting the time the game spent paused, when the phase
+// is skipped or when ctx is done. A restored game gets the time its phase had
+// left instead. Callers must call stop once the phase is over.
+func (s *service) startPhaseTimer(ctx context.Context, d time.Duration) phaseTimer {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	d = s.restoredTimeLeft(d)
+	s.clock.start(d)
+	skipCh := s.clock.armSkip()
+	if err := s.saveState(ctx); err != nil {
+		s.log.ErrorContext(ctx, "failed to save game state", "error", err)
+	}
+
+	go func() {
+		defer close(done)
+		defer s.clock.disarmSkip(skipCh)
+
+		for {
+			paused, remaining, pausedCh, resumedCh := s.clock.state()
+			if paused {
+				select {
+				case <-resumedCh:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			timer := time.NewTimer(remaining)
+			select {
+			case <-timer.C:
+				return
+			case <-skipCh:
+				timer.Stop()
+				return
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-pausedCh:
+				timer.Stop()
+			}
+		}
+	}()
+
+	return phaseTimer{done: done, stop: cancel}
+}
+
+// wait blocks for d, see startPhaseTimer. It returns the context's error if
+// the game is stopped while waiting.
+func (s *service) wait(ctx context.Context, d time.Duration) error {
+	timer := s.startPhaseTimer(ctx, d)
+	defer timer.stop()
+
+	<-timer.done
+
+	return ctx.Err()
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/Jubris-Knifes/wgj25-back/codec"
+	"github.com/Jubris-Knifes/wgj25-back/transport"
+	"github.com/olahol/melody"
+)
+
+const CodecKey = transport.CodecKey
+
+// sessionCodec returns the encoding negotiated with the session at handshake,
+// JSON until then.
+func sessionCodec(session *melody.Session) codec.Codec {
+	return transport.SessionCodec(session)
+}
+
+func (s *service) decode(session *melody.Session, data []byte, v any) error {
+	return sessionCodec(session).Unmarshal(data, v)
+}
+
+// write sends event to a single session in its own encoding.
+func (s *service) write(session *melody.Session, event any) error {
+	if err := transport.Write(session, event); err != nil {
+		s.log.ErrorContext(session.Request.Context(), "failed to write event", "error", err, "codec", sessionCodec(session).Name())
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) broadcast(event any) error {
+	return s.transport.Broadcast(event)
+}
+
+// broadcastOthers sends event to every session but except, which already
+// learned about it in the response to its own request.
+func (s *service) broadcastOthers(event any, except *melody.Session) error {
+	var errs []error
+	for _, session := range s.sessions.All() {
+		if session == except || session.IsClosed() {
+			continue
+		}
+
+		if err := transport.Write(session, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
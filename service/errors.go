@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/Jubris-Knifes/wgj25-back/repository"
+)
+
+var (
+	ErrGameNotRunning     = errors.New("game not running")
+	ErrGameAlreadyPaused  = errors.New("game already paused")
+	ErrGameNotPaused      = errors.New("game not paused")
+	ErrNoTimedPhase       = errors.New("no timed phase running")
+	ErrInvalidPlayerCount = errors.New("invalid player count")
+	ErrPlayerNotAtTable   = errors.New("player not at the table")
+	ErrPlayersNotReady    = errors.New("not every player is ready")
+	ErrGameAlreadyRunning = errors.New("game already running")
+	ErrUnknownRoom        = errors.New("unknown room code")
+)
+
+// clientError is an error caused by what the client sent, reported back to it
+// as is.
+type clientError struct {
+	code    models.ErrorCode
+	message string
+}
+
+func newClientError(code models.ErrorCode, message string) error {
+	return &clientError{code: code, message: message}
+}
+
+func (e *clientError) Error() string {
+	return e.message
+}
+
+// errorCode maps an error to the code sent to clients. Anything we don't know
+// about is reported as an internal error without leaking its details.
+func errorCode(err error) (models.ErrorCode, string) {
+	var clientErr *clientError
+	if errors.As(err, &clientErr) {
+		return clientErr.code, clientErr.message
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrPlayerCountTooHigh):
+		return models.ErrorCodePlayerCountTooHigh, err.Error()
+	case errors.Is(err, repository.ErrPlayerAlreadyExists):
+		return models.ErrorCodeNameTaken, "name already taken, resume with your token to get your seat back"
+	case errors.Is(err, repository.ErrUnknownResumeToken):
+		return models.ErrorCodeNotAllowed, err.Error()
+	case errors.Is(err, ErrInvalidPlayerCount):
+		return models.ErrorCodeInvalidPlayerCount, err.Error()
+	case errors.Is(err, ErrPlayersNotReady):
+		return models.ErrorCodePlayersNotReady, err.Error()
+	case errors.Is(err, ErrGameAlreadyRunning):
+		return models.ErrorCodeGameAlreadyRunning, err.Error()
+	case errors.Is(err, ErrUnknownRoom):
+		return models.ErrorCodeUnknownRoom, err.Error()
+	case errors.Is(err, ErrGameNotRunning):
+		return models.ErrorCodeGameNotRunning, err.Error()
+	case errors.Is(err, ErrGameAlreadyPaused):
+		return models.ErrorCodeGameAlreadyPaused, err.Error()
+	case errors.Is(err, ErrGameNotPaused):
+		return models.ErrorCodeGameNotPaused, err.Error()
+	}
+
+	return models.ErrorCodeInternal, "internal error"
+}
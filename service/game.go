@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+)
+
+const (
+	minPlayersPerGame = models.MinTableSize
+
+	maxStepAttempts = 3
+	stepRetryDelay  = 2 * time.Second
+)
+
+// hasNextRound checks the end condition of the table once a round is over.
+func (s *service) hasNextRound(ctx context.Context) bool {
+	end := s.rules().EndCondition
+
+	s.gameMu.Lock()
+	roundsPlayed := s.roundsPlayed
+	s.gameMu.Unlock()
+
+	if end.Rounds > 0 && roundsPlayed >= end.Rounds {
+		return false
+	}
+
+	if end.TargetPoints == 0 {
+		return true
+	}
+
+	scores, err := s.repo.GetPlayerScoresOf(ctx, s.seatedPlayers())
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get player scores", "error", err)
+		return true
+	}
+
+	return !slices.ContainsFunc(scores, func(score models.Score) bool { return score.Points >= end.TargetPoints })
+}
+
+// seatPlayers seats the players at the table when the game's first round
+// starts, with the scores of the last game cleared. They keep their seats for
+// the whole game, whoever joins or leaves, and the bot plays the seats of
+// players who are gone. Restored games come with their seats.
+func (s *service) seatPlayers(ctx context.Context) ([]int, error) {
+	if seats := s.seatedPlayers(); len(seats) > 0 {
+		return seats, nil
+	}
+
+	playerIDs, err := s.repo.GetActivePlayerIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(playerIDs) < minPlayersPerGame || len(playerIDs) > s.rules().TableSize {
+		return nil, ErrInvalidPlayerCount
+	}
+
+	if err := s.repo.ResetScores(ctx); err != nil {
+		return nil, err
+	}
+
+	s.gameMu.Lock()
+	s.seats = slices.Clone(playerIDs)
+	s.gameMu.Unlock()
+
+	return playerIDs, nil
+}
+
+func (s *service) seatedPlayers() []int {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	return slices.Clone(s.seats)
+}
+
+// startGame launches the game loop on its own goroutine. It does nothing if a
+// game is already running, or a restored one is waiting for its players.
+func (s *service) startGame() bool {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	if s.restored != nil {
+		s.log.Warn("restored game waiting for its players, not starting another one")
+		return false
+	}
+
+	return s.launchGame(models.ResumeRound)
+}
+
+// restartRound cancels the loop of the running game and starts another one
+// that deals the current round again. The seats, scores and rounds played are
+// kept.
+func (s *service) restartRound() bool {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	if s.gameCancel == nil {
+		return false
+	}
+
+	s.gameCancel()
+	s.gameCancel = nil
+	s.timeLeft = nil
+	s.turn.reset()
+
+	return s.launchGame(models.ResumeRound)
+}
+
+// launchGame starts the game loop from step. gameMu must be held.
+func (s *service) launchGame(step models.ResumeStep) bool {
+	if s.gameCancel != nil {
+		s.log.Warn("game already running, not starting another one")
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.gameCancel = cancel
+	s.step = step
+	s.bot, s.autoPick = s.strategies(s.settings.Points)
+	s.clock.reset()
+
+	go s.runGame(ctx)
+
+	return true
+}
+
+// runGame plays steps until the game is stopped, runs out of rounds or one
+// of its steps keeps failing.
+func (s *service) runGame(ctx context.Context) {
+	for ctx.Err() == nil {
+		step := s.currentStep()
+		if err := s.runStep(ctx, string(step), s.playStep); err != nil {
+			s.abortGame(ctx, err)
+			return
+		}
+
+		if step == models.ResumeEndOfRound && !s.hasNextRound(ctx) {
+			s.endGameOf(ctx, "no rounds left")
+			return
+		}
+	}
+}
+
+// playStep plays the game from its last checkpoint, so a step that failed
+// halfway through doesn't play again what it already settled.
+func (s *service) playStep(ctx context.Context) error {
+	switch step := s.currentStep(); step {
+	case models.ResumeRound:
+		return s.startRound(ctx)
+	case models.ResumeTurn:
+		return s.startTurn(ctx)
+	case models.ResumeNextTurn:
+		return s.prepareForNextTurn(ctx)
+	case models.ResumeEndOfRound:
+		return s.endOfRound(ctx)
+	default:
+		return fmt.Errorf("unknown game step %q", step)
+	}
+}
+
+// runStep runs a step of the game loop, playing it again from the start if
+// it fails. Panics are turned into errors so they only take this game down.
+func (s *service) runStep(ctx context.Context, step string, fn func(context.Context) error) error {
+	var err error
+	for attempt := 1; attempt <= maxStepAttempts; attempt++ {
+		err = recoverStep(ctx, fn)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		retrying := attempt < maxStepAttempts
+		s.log.ErrorContext(ctx, "game step failed", "step", step, "attempt", attempt, "retrying", retrying, "error", err)
+
+		event := models.GameErrorEvent{
+			Type: models.EventTypeGameError,
+			EventData: models.GameError{
+				Step:     step,
+				Retrying: retrying,
+			},
+		}
+		if err := s.broadcast(event); err != nil {
+			s.log.ErrorContext(ctx, "failed to broadcast game_error event", "error", err)
+		}
+
+		if !retrying {
+			break
+		}
+
+		if err := s.wait(ctx, stepRetryDelay); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func recoverStep(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// abortGame ends a game whose loop failed. Games stopped on purpose end
+// without a word, whoever stopped them already told the clients.
+func (s *service) abortGame(ctx context.Context, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.log.ErrorContext(ctx, "aborting game", "error", err)
+	s.endGameOf(ctx, "game aborted after an error")
+}
+
+// stopGame cancels the running game loop, if any, or drops the restored game
+// waiting for its players. Every phase returns as soon as it notices its
+// context is done.
+func (s *service) stopGame() bool {
+	return s.stopGameOf(nil)
+}
+
+// stopGameOf stops the game whose loop runs with loop, unless it was already
+// stopped, so a loop can't stop the game started after its own. A nil loop
+// stops whatever game there is.
+func (s *service) stopGameOf(loop context.Context) bool {
+	if !s.cancelGame(loop) {
+		return false
+	}
+
+	s.clearState(context.Background())
+
+	return true
+}
+
+func (s *service) cancelGame(loop context.Context) bool {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	if s.gameCancel == nil && s.restored == nil {
+		return false
+	}
+
+	// Loops are only ever cancelled here, a cancelled one is not the one
+	// running.
+	if loop != nil && loop.Err() != nil {
+		return false
+	}
+
+	if s.gameCancel != nil {
+		s.gameCancel()
+	}
+	s.gameCancel = nil
+	s.restored = nil
+	s.timeLeft = nil
+	s.roundsPlayed = 0
+	s.seats = nil
+	s.phase = models.PhaseLobby
+	s.turn.reset()
+	s.afk.reset()
+	s.lobby.resetReady()
+	s.clock.reset()
+
+	return true
+}
+
+// setPhase records the phase the game loop of ctx entered. Loops of games that
+// were already stopped are ignored.
+func (s *service) setPhase(ctx context.Context, phase models.Phase) {
+	s.gameMu.Lock()
+	if s.gameCancel == nil || ctx.Err() != nil {
+		s.gameMu.Unlock()
+		return
+	}
+
+	s.log.Debug("entering phase", "phase", phase)
+	s.phase = phase
+	s.gameMu.Unlock()
+
+	if err := s.saveState(ctx); err != nil {
+		s.log.ErrorContext(ctx, "failed to save game state", "error", err)
+	}
+}
+
+func (s *service) currentPhase() models.Phase {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	return s.phase
+}
+
+// hasGame reports whether a game is running or restored and waiting for its
+// players.
+func (s *service) hasGame() bool {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	return s.gameCancel != nil || s.restored != nil
+}
+
+func (s *service) isGameRunning() bool {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	return s.gameCancel != nil
+}
+
+func (s *service) endGame(reason string) {
+	s.endGameOf(nil, reason)
+}
+
+// endGameOf ends the game whose loop runs with loop, see stopGameOf.
+func (s *service) endGameOf(loop context.Context, reason string) {
+	if !s.stopGameOf(loop) && loop != nil {
+		return
+	}
+
+	s.log.Info("Ending game", "reason", reason)
+
+	event := models.GameEndedEvent{
+		Type: models.EventTypeGameEnded,
+		EventData: models.GameEnded{
+			Reason: reason,
+		},
+	}
+
+	if err := s.broadcast(event); err != nil {
+		s.log.Error("failed to broadcast game_ended event", "error", err)
+	}
+
+	s.broadcastRoster(context.Background())
+}
+
+func (s *service) pauseGame(ctx context.Context) error {
+	if !s.isGameRunning() {
+		return ErrGameNotRunning
+	}
+
+	remaining, ok := s.clock.pause()
+	if !ok {
+		return ErrGameAlreadyPaused
+	}
+
+	s.log.InfoContext(ctx, "game paused", "remaining", remaining)
+
+	if err := s.saveState(ctx); err != nil {
+		s.log.ErrorContext(ctx, "failed to save game state", "error", err)
+	}
+
+	event := models.GamePausedEvent{
+		Type: models.EventTypeGamePaused,
+		EventData: models.GamePaused{
+			RemainingTimeout: remaining.Milliseconds(),
+		},
+	}
+
+	if err := s.broadcast(event); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast game_paused event", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) resumeGame(ctx context.Context) error {
+	if !s.isGameRunning() {
+		return ErrGameNotRunning
+	}
+
+	remaining, ok := s.clock.resume()
+	if !ok {
+		return ErrGameNotPaused
+	}
+
+	s.log.InfoContext(ctx, "game resumed", "remaining", remaining)
+
+	event := models.GameResumedEvent{
+		Type: models.EventTypeGameResumed,
+		EventData: models.GameResumed{
+			Timeout:  remaining.Milliseconds(),
+			Deadline: deadlineIn(remaining),
+		},
+	}
+
+	if err := s.broadcast(event); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast game_resumed event", "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Jubris-Knifes/wgj25-back/codec"
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/Jubris-Knifes/wgj25-back/transport"
+	"github.com/olahol/melody"
+)
+
+// negotiateProtocol picks the protocol version to speak with a client. Newer
+// clients are asked to fall back to our version, clients older than the
+// minimum we support are rejected.
+func negotiateProtocol(clientVersion int) (int, error) {
+	switch {
+	case clientVersion < models.MinProtocolVersion:
+		return 0, fmt.Errorf("protocol version %d is no longer supported, minimum is %d",
+			clientVersion, models.MinProtocolVersion)
+	case clientVersion > models.ProtocolVersion:
+		return models.ProtocolVersion, nil
+	}
+
+	return clientVersion, nil
+}
+
+// handleHelloEvent answers with a hello_response. Rejected clients already got
+// their reason in it, so it only returns an error for repeated handshakes.
+func (s *service) handleHelloEvent(session *melody.Session, hello models.Hello) error {
+	ctx := session.Request.Context()
+
+	if _, ok := getAs[int](s.log, session, ProtocolVersionKey); ok {
+		s.log.WarnContext(ctx, "handshake already done", "remote_address", session.RemoteAddr().String())
+		return newClientError(models.ErrorCodeNotAllowed, "handshake already done")
+	}
+
+	if !hello.Role.IsValid() {
+		s.rejectHandshake(session, hello, fmt.Sprintf("unknown role %q", hello.Role))
+		return nil
+	}
+
+	version, err := negotiateProtocol(hello.ProtocolVersion)
+	if err != nil {
+		s.rejectHandshake(session, hello, err.Error())
+		return nil
+	}
+
+	encoding := codec.Negotiate(hello.Encodings)
+
+	session.Set(ProtocolVersionKey, version)
+	s.sessions.SetRole(session, hello.Role)
+
+	s.log.InfoContext(ctx, "handshake accepted",
+		"remote_address", session.RemoteAddr().String(),
+		"client_version", hello.ProtocolVersion,
+		"protocol_version", version,
+		"role", hello.Role,
+		"encoding", encoding.Name(),
+	)
+
+	response := models.HelloResponseEvent{
+		Type: models.EventTypeHelloResponse,
+		EventData: models.HelloResponse{
+			Accepted:        true,
+			ProtocolVersion: version,
+			Role:            hello.Role,
+			Encoding:        encoding.Name(),
+		},
+	}
+
+	// The response still goes out as JSON, everything after it uses the
+	// negotiated encoding.
+	if err := s.write(session, response); err != nil {
+		s.log.ErrorContext(ctx, "failed to send hello_response event", "error", err)
+		return err
+	}
+
+	session.Set(CodecKey, encoding)
+
+	if err := s.sendStateSnapshot(ctx, session); err != nil {
+		s.log.ErrorContext(ctx, "failed to send state snapshot", "error", err)
+	}
+
+	return nil
+}
+
+// rejectHandshake tells the client why it can't join and closes its session.
+func (s *service) rejectHandshake(session *melody.Session, hello models.Hello, reason string) {
+	ctx := session.Request.Context()
+
+	s.log.WarnContext(ctx, "handshake rejected",
+		"remote_address", session.RemoteAddr().String(),
+		"client_version", hello.ProtocolVersion,
+		"role", hello.Role,
+		"reason", reason,
+	)
+
+	response := models.HelloResponseEvent{
+		Type: models.EventTypeHelloResponse,
+		EventData: models.HelloResponse{
+			Accepted:        false,
+			ProtocolVersion: models.ProtocolVersion,
+			Role:            hello.Role,
+			Encoding:        sessionCodec(session).Name(),
+			Reason:          reason,
+		},
+	}
+
+	if err := s.write(session, response); err != nil {
+		s.log.ErrorContext(ctx, "failed to send hello_response event", "error", err)
+	}
+
+	if err := session.CloseWithMsg(melody.FormatCloseMessage(closeCodeRejected, reason)); err != nil {
+		s.log.ErrorContext(ctx, "failed to close rejected session", "error", err)
+	}
+}
+
+// handlePingEvent answers with the server's clock, so the client can work out
+// how far its own clock is off and count down to our deadlines.
+func (s *service) handlePingEvent(session *melody.Session, ping models.Ping) error {
+	pong := models.PongEvent{
+		Type: models.EventTypePong,
+		EventData: models.Pong{
+			ClientTime: ping.ClientTime,
+			ServerTime: time.Now().UnixMilli(),
+		},
+	}
+
+	return s.write(session, pong)
+}
+
+func sessionRole(session *melody.Session) models.Role {
+	return transport.SessionRole(session)
+}
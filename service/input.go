@@ -0,0 +1,86 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+)
+
+// phaseInput hands the actions players send during one phase to the game
+// loop. Input is only taken while the phase is open, from the players it was
+// opened for and once per player, so a handler never blocks on it and nothing
+// sent late is read by a later phase.
+type phaseInput[T any] struct {
+	name string
+
+	mu        sync.Mutex
+	ch        chan T
+	playerIDs []int
+	received  []int
+	validate  func(T) error
+}
+
+func newPhaseInput[T any](name string) *phaseInput[T] {
+	return &phaseInput[T]{name: name}
+}
+
+// open starts taking input from playerIDs. validate, if not nil, checks each
+// action against the state of the phase before it is accepted.
+func (in *phaseInput[T]) open(playerIDs []int, validate func(T) error) <-chan T {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.ch = make(chan T, len(playerIDs))
+	in.playerIDs = slices.Clone(playerIDs)
+	in.received = make([]int, 0, len(playerIDs))
+	in.validate = validate
+
+	return in.ch
+}
+
+// close stops taking input. Anything the game loop didn't read is dropped
+// along with the channel.
+func (in *phaseInput[T]) close() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.ch = nil
+	in.playerIDs = nil
+	in.received = nil
+	in.validate = nil
+}
+
+func (in *phaseInput[T]) send(playerID int, action T) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	if in.ch == nil {
+		return newClientError(models.ErrorCodeWrongPhase, fmt.Sprintf("no %s open", in.name))
+	}
+
+	if !slices.Contains(in.playerIDs, playerID) {
+		return newClientError(models.ErrorCodeNotAllowed, fmt.Sprintf("not your %s", in.name))
+	}
+
+	if slices.Contains(in.received, playerID) {
+		return newClientError(models.ErrorCodeDuplicateAction, fmt.Sprintf("%s already received", in.name))
+	}
+
+	if in.validate != nil {
+		if err := in.validate(action); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case in.ch <- action:
+	default:
+		return newClientError(models.ErrorCodeDuplicateAction, fmt.Sprintf("%s already received", in.name))
+	}
+
+	in.received = append(in.received, playerID)
+
+	return nil
+}
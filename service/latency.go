@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Jubris-Knifes/wgj25-back/config"
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/Jubris-Knifes/wgj25-back/transport"
+)
+
+// monitorConnections pings every session and tells the hub how the players'
+// connections are doing, for as long as the server runs.
+func (s *service) monitorConnections() {
+	interval := time.Duration(config.Get().Latency.PingIntervalMilliseconds) * time.Millisecond
+	// Pings still unanswered by the next tick are lost, with some room for
+	// the ticker running a little early.
+	pongTimeout := interval * 3 / 4
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, session := range s.sessions.All() {
+			if err := transport.Ping(session, pongTimeout); err != nil {
+				s.log.Debug("failed to ping session", "remote_address", session.RemoteAddr().String(), "error", err)
+			}
+		}
+
+		s.sendConnectionQuality(context.Background())
+	}
+}
+
+func (s *service) sendConnectionQuality(ctx context.Context) {
+	var players []models.PlayerConnection
+	for _, playerID := range s.sessions.PlayerIDs() {
+		stats, ok := s.playerLatency(playerID)
+		if !ok {
+			continue
+		}
+
+		players = append(players, models.PlayerConnection{
+			PlayerID:            playerID,
+			Quality:             connectionQuality(stats),
+			AverageMilliseconds: stats.AverageMilliseconds,
+			JitterMilliseconds:  stats.JitterMilliseconds,
+		})
+	}
+
+	if len(players) == 0 {
+		return
+	}
+
+	event := models.ConnectionQualityEvent{
+		Type: models.EventTypeConnectionQuality,
+		EventData: models.ConnectionQuality{
+			Players: players,
+		},
+	}
+
+	if err := s.publish(ctx, toHub(event)); err != nil {
+		s.log.ErrorContext(ctx, "failed to send connection_quality event", "error", err)
+	}
+}
+
+// playerLatency returns the stats of the best connection a player has open.
+func (s *service) playerLatency(playerID int) (models.LatencyStats, bool) {
+	var best models.LatencyStats
+	found := false
+	for _, session := range s.sessions.Player(playerID) {
+		stats, ok := transport.Latency(session)
+		if ok && (!found || stats.AverageMilliseconds < best.AverageMilliseconds) {
+			best, found = stats, true
+		}
+	}
+
+	return best, found
+}
+
+func connectionQuality(stats models.LatencyStats) models.ConnectionQualityLevel {
+	rtt := stats.AverageMilliseconds + stats.JitterMilliseconds
+	switch {
+	case rtt >= int64(config.Get().Latency.PoorMilliseconds):
+		return models.ConnectionQualityPoor
+	case rtt >= int64(config.Get().Latency.FairMilliseconds):
+		return models.ConnectionQualityFair
+	}
+
+	return models.ConnectionQualityGood
+}
+
+// latencyGrace is how much longer to wait for input from playerIDs than the
+// timeout they were shown, so a slow connection doesn't cost them their turn.
+func (s *service) latencyGrace(playerIDs ...int) time.Duration {
+	var slowest int64
+	for _, playerID := range playerIDs {
+		if stats, ok := s.playerLatency(playerID); ok {
+			slowest = max(slowest, stats.AverageMilliseconds)
+		}
+	}
+
+	grace := time.Duration(slowest) * time.Millisecond
+	return min(grace, time.Duration(config.Get().Latency.MaxGraceMilliseconds)*time.Millisecond)
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/Jubris-Knifes/wgj25-back/transport"
+	"github.com/olahol/melody"
+)
+
+// lobby is who is ready to play and who hosts the table. The host is the
+// player who has been at the table the longest; they and the hub can start
+// the game once everyone is ready.
+type lobby struct {
+	mu     sync.Mutex
+	hostID int
+	ready  []int
+}
+
+func (l *lobby) join(playerID int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.hostID == 0 {
+		l.hostID = playerID
+	}
+}
+
+// leave drops a player from the lobby, handing the table to the first of the
+// remaining players if they hosted it.
+func (l *lobby) leave(playerID int, remaining []int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ready = slices.DeleteFunc(l.ready, func(id int) bool { return id == playerID })
+	if l.hostID != playerID {
+		return
+	}
+
+	l.hostID = 0
+	if len(remaining) > 0 {
+		l.hostID = remaining[0]
+	}
+}
+
+func (l *lobby) setReady(playerID int, ready bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ready = slices.DeleteFunc(l.ready, func(id int) bool { return id == playerID })
+	if ready {
+		l.ready = append(l.ready, playerID)
+	}
+}
+
+func (l *lobby) isReady(playerID int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return slices.Contains(l.ready, playerID)
+}
+
+func (l *lobby) host() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.hostID
+}
+
+func (l *lobby) resetReady() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ready = nil
+}
+
+// roster lists the players at the table, with whether they are connected and
+// ready.
+func (s *service) roster(ctx context.Context) ([]models.Player, error) {
+	players, err := s.repo.GetActivePlayers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	connected := s.sessions.PlayerIDs()
+	for i := range players {
+		players[i].Connected = slices.Contains(connected, players[i].PlayerID)
+		players[i].Ready = s.lobby.isReady(players[i].PlayerID)
+	}
+
+	return players, nil
+}
+
+func (s *service) broadcastRoster(ctx context.Context) {
+	players, err := s.roster(ctx)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get roster", "error", err)
+		return
+	}
+
+	event := models.LobbyRosterEvent{
+		Type: models.EventTypeLobbyRoster,
+		EventData: models.LobbyRoster{
+			HostPlayerID: s.lobby.host(),
+			Players:      players,
+		},
+	}
+
+	if err := s.publish(ctx, everyone(event)); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast lobby_roster event", "error", err)
+	}
+}
+
+// playerLeft takes a player who closed their last session out of the lobby.
+func (s *service) playerLeft(ctx context.Context, playerID int) {
+	remaining, err := s.repo.GetActivePlayerIDs(ctx)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get active player IDs", "error", err)
+	}
+
+	s.lobby.leave(playerID, remaining)
+	s.broadcastRoster(ctx)
+}
+
+func (s *service) handleSetReadyEvent(session *melody.Session, setReady models.SetReady) error {
+	ctx := session.Request.Context()
+
+	playerID, ok := transport.SessionPlayerID(session)
+	if !ok {
+		return newClientError(models.ErrorCodeNotAllowed, "set a name before getting ready")
+	}
+
+	s.lobby.setReady(playerID, setReady.Ready)
+	s.log.InfoContext(ctx, "player readiness changed", "player_id", playerID, "ready", setReady.Ready)
+	s.broadcastRoster(ctx)
+
+	return nil
+}
+
+func (s *service) handleStartGameEvent(session *melody.Session, _ models.StartGame) error {
+	if !s.controlsTable(session) {
+		return newClientError(models.ErrorCodeNotAllowed, "only the host can start the game")
+	}
+
+	return s.startFromLobby(session.Request.Context())
+}
+
+// startFromLobby starts the game once the table has enough players and all
+// of them are ready.
+func (s *service) startFromLobby(ctx context.Context) error {
+	players, err := s.roster(ctx)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get roster", "error", err)
+		return err
+	}
+
+	if len(players) < minPlayersPerGame || len(players) > s.rules().TableSize {
+		return ErrInvalidPlayerCount
+	}
+
+	if slices.ContainsFunc(players, func(player models.Player) bool { return !player.Ready }) {
+		return ErrPlayersNotReady
+	}
+
+	if !s.startGame() {
+		return ErrGameAlreadyRunning
+	}
+
+	s.log.InfoContext(ctx, "game started from the lobby", "players", len(players))
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+)
+
+// The game loop saves its state at every phase it enters, and checkpoints
+// the step it would pick up from once a step has left the repository in a
+// state that step can start from. Hands, scores and the current player are
+// saved by the steps themselves.
+
+// checkpoint records that the game loop of ctx can pick up from step, after
+// a failed attempt or a restart.
+func (s *service) checkpoint(ctx context.Context, step models.ResumeStep) error {
+	s.gameMu.Lock()
+	if s.gameCancel == nil || ctx.Err() != nil {
+		s.gameMu.Unlock()
+		return ctx.Err()
+	}
+	s.step = step
+	// Past the step it was restored in, the game's phases get their full
+	// time.
+	s.timeLeft = nil
+	s.gameMu.Unlock()
+
+	return s.saveState(ctx)
+}
+
+// phaseTimeLeft is the time the phase a game was restored in had left.
+type phaseTimeLeft struct {
+	phase models.Phase
+	left  time.Duration
+}
+
+func (s *service) currentStep() models.ResumeStep {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	return s.step
+}
+
+// saveState saves the state of the running game. It does nothing once the
+// game is stopped, so a stopped loop can't bring its state back.
+func (s *service) saveState(ctx context.Context) error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.gameMu.Lock()
+	if s.gameCancel == nil || ctx.Err() != nil {
+		s.gameMu.Unlock()
+		return nil
+	}
+	left, _ := s.clock.timeLeft()
+	state := models.GameState{
+		Resume:       s.step,
+		Phase:        s.phase,
+		TimeLeft:     left.Milliseconds(),
+		RoundsPlayed: s.roundsPlayed,
+		Settings:     s.settings,
+		PlayerIDs:    slices.Clone(s.seats),
+	}
+	s.gameMu.Unlock()
+
+	return s.repo.SaveGameState(ctx, state)
+}
+
+// restoredTimeLeft shortens the first timer of the phase a restored game was in
+// to the time that phase had left.
+func (s *service) restoredTimeLeft(d time.Duration) time.Duration {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	if s.timeLeft == nil || s.timeLeft.phase != s.phase {
+		return d
+	}
+
+	left := s.timeLeft.left
+	s.timeLeft = nil
+
+	return min(d, left)
+}
+
+// Shutdown saves the state of the running game with the time its phase has
+// left, before the server stops.
+func (s *service) Shutdown(ctx context.Context) {
+	if err := s.saveState(ctx); err != nil {
+		s.log.ErrorContext(ctx, "failed to save game state", "error", err)
+	}
+}
+
+func (s *service) clearState(ctx context.Context) {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	if err := s.repo.ClearGameState(ctx); err != nil {
+		s.log.ErrorContext(ctx, "failed to clear game state", "error", err)
+	}
+}
+
+// Restore loads the game that was running when the server stopped. The game
+// waits until every player in it is back with their resume token, then plays
+// the step it was in again, with the time left on the phase it was in.
+func (s *service) Restore(ctx context.Context) error {
+	// Nobody is connected yet.
+	if err := s.repo.DeactivatePlayers(ctx); err != nil {
+		return err
+	}
+
+	state, ok, err := s.repo.GetGameState(ctx)
+	if err != nil || !ok {
+		return err
+	}
+
+	s.gameMu.Lock()
+	s.restored = &state
+	s.phase = state.Phase
+	s.step = state.Resume
+	s.roundsPlayed = state.RoundsPlayed
+	s.seats = state.PlayerIDs
+	if state.TimeLeft > 0 {
+		s.timeLeft = &phaseTimeLeft{phase: state.Phase, left: milliseconds(int(state.TimeLeft))}
+	}
+	if state.Settings.Validate() == nil {
+		s.settings = state.Settings
+	}
+	s.gameMu.Unlock()
+
+	s.log.InfoContext(ctx, "restored unfinished game",
+		"phase", state.Phase,
+		"resume", state.Resume,
+		"player_ids", state.PlayerIDs,
+	)
+
+	return nil
+}
+
+// resumeRestoredGame starts the loop of the restored game once all of its
+// players are back.
+func (s *service) resumeRestoredGame(ctx context.Context) {
+	s.gameMu.Lock()
+	restored := s.restored
+	s.gameMu.Unlock()
+
+	if restored == nil {
+		return
+	}
+
+	active, err := s.repo.GetActivePlayerIDs(ctx)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get active player IDs", "error", err)
+		return
+	}
+
+	missing := slices.DeleteFunc(slices.Clone(restored.PlayerIDs), func(id int) bool {
+		return slices.Contains(active, id)
+	})
+	if len(missing) > 0 {
+		s.log.InfoContext(ctx, "waiting for players of the restored game", "missing", missing)
+		return
+	}
+
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	if s.restored != restored {
+		return
+	}
+	s.restored = nil
+
+	s.log.InfoContext(ctx, "resuming restored game", "resume", restored.Resume)
+	s.launchGame(restored.Resume)
+}
+
+func newResumeToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
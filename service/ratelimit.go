@@ -0,0 +1,115 @@
+package service
+
+import (
+	"expvar"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Jubris-Knifes/wgj25-back/config"
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/olahol/melody"
+)
+
+const RateLimiterKey = "rate_limiter"
+
+var (
+	messagesRateLimited = expvar.NewInt("messages_rate_limited")
+	sessionsRateLimited = expvar.NewInt("sessions_rate_limited")
+)
+
+// rateLimiter is a token bucket refilled at rate tokens per second, holding
+// at most burst tokens. It also keeps track of the messages it turned down
+// within the last window, so occasional bursts over a long game add up to
+// nothing.
+type rateLimiter struct {
+	mu         sync.Mutex
+	rate       float64
+	burst      float64
+	tokens     float64
+	last       time.Time
+	window     time.Duration
+	violations []time.Time
+}
+
+func newRateLimiter(rate float64, burst int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		window: window,
+	}
+}
+
+// allow takes a token if there is one. Otherwise it returns false along with
+// the number of messages turned down within the window.
+func (l *rateLimiter) allow(now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	l.violations = slices.DeleteFunc(l.violations, func(at time.Time) bool {
+		return now.Sub(at) >= l.window
+	})
+
+	if l.tokens < 1 {
+		l.violations = append(l.violations, now)
+		return false, len(l.violations)
+	}
+
+	l.tokens--
+	return true, len(l.violations)
+}
+
+func (s *service) sessionRateLimiter(session *melody.Session) *rateLimiter {
+	if limiter, ok := getAs[*rateLimiter](s.log, session, RateLimiterKey); ok {
+		return limiter
+	}
+
+	limits := config.Get().Limits
+	window := time.Duration(limits.ViolationWindowSeconds) * time.Second
+	limiter := newRateLimiter(limits.MessagesPerSecond, limits.MessageBurst, window)
+	session.Set(RateLimiterKey, limiter)
+
+	return limiter
+}
+
+// allowMessage reports whether the session may send another message. Sessions
+// going over the limit are told so, and disconnected once they do it too often.
+func (s *service) allowMessage(session *melody.Session) bool {
+	ctx := session.Request.Context()
+
+	allowed, violations := s.sessionRateLimiter(session).allow(time.Now())
+	if allowed {
+		return true
+	}
+
+	messagesRateLimited.Add(1)
+
+	maxViolations := config.Get().Limits.MaxViolations
+	if violations < maxViolations {
+		s.log.WarnContext(ctx, "message rate limited",
+			"remote_address", session.RemoteAddr().String(),
+			"violations", violations,
+		)
+		s.respond(session, "", newClientError(models.ErrorCodeRateLimited, "too many messages, slow down"))
+		return false
+	}
+
+	s.log.WarnContext(ctx, "disconnecting rate limited session",
+		"remote_address", session.RemoteAddr().String(),
+		"violations", violations,
+	)
+	sessionsRateLimited.Add(1)
+
+	reason := fmt.Sprintf("rate limit exceeded %d times in %ds", violations, config.Get().Limits.ViolationWindowSeconds)
+	if err := session.CloseWithMsg(melody.FormatCloseMessage(closeCodeRateLimited, reason)); err != nil {
+		s.log.ErrorContext(ctx, "failed to close rate limited session", "error", err)
+	}
+
+	return false
+}
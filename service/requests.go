@@ -0,0 +1,176 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/Jubris-Knifes/wgj25-back/transport"
+	"github.com/olahol/melody"
+)
+
+const (
+	RequestLogKey = "request_log"
+
+	requestLogSize = 64
+)
+
+// requestLog remembers the response sent for the last requests of a player,
+// or of a session that has no player yet, so retried sends get the same
+// answer instead of being applied twice.
+type requestLog struct {
+	mu        sync.Mutex
+	responses map[string]any
+	order     []string
+}
+
+func newRequestLog() *requestLog {
+	return &requestLog{
+		responses: make(map[string]any, requestLogSize),
+		order:     make([]string, 0, requestLogSize),
+	}
+}
+
+// begin records requestID as seen. It returns false, along with the response
+// sent the first time if there is one yet, if the id was already seen.
+func (l *requestLog) begin(requestID string) (any, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if response, ok := l.responses[requestID]; ok {
+		return response, false
+	}
+
+	if len(l.order) == requestLogSize {
+		delete(l.responses, l.order[0])
+		l.order = l.order[1:]
+	}
+
+	l.responses[requestID] = nil
+	l.order = append(l.order, requestID)
+
+	return nil, true
+}
+
+func (l *requestLog) finish(requestID string, response any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.responses[requestID]; ok {
+		l.responses[requestID] = response
+	}
+}
+
+// requestLogs holds the request logs of players. They outlive sessions, so a
+// request retried after reconnecting is still recognised.
+type requestLogs struct {
+	mu       sync.Mutex
+	byPlayer map[int]*requestLog
+}
+
+func (l *requestLogs) player(playerID int) *requestLog {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.byPlayer == nil {
+		l.byPlayer = map[int]*requestLog{}
+	}
+
+	log, ok := l.byPlayer[playerID]
+	if !ok {
+		log = newRequestLog()
+		l.byPlayer[playerID] = log
+	}
+
+	return log
+}
+
+func (s *service) requestLog(session *melody.Session) *requestLog {
+	if playerID, ok := transport.SessionPlayerID(session); ok {
+		return s.requests.player(playerID)
+	}
+
+	if log, ok := getAs[*requestLog](s.log, session, RequestLogKey); ok {
+		return log
+	}
+
+	log := newRequestLog()
+	session.Set(RequestLogKey, log)
+
+	return log
+}
+
+// replayResponse reports whether requestID was already handled for the
+// session's player. The first response is sent again, if it is ready.
+func (s *service) replayResponse(session *melody.Session, requests *requestLog, requestID string) bool {
+	if requestID == "" {
+		return false
+	}
+
+	response, isNew := requests.begin(requestID)
+	if isNew {
+		return false
+	}
+
+	s.log.InfoContext(session.Request.Context(), "dropping duplicate request", "request_id", requestID)
+
+	if response != nil {
+		if err := s.write(session, response); err != nil {
+			s.log.ErrorContext(session.Request.Context(), "failed to resend response", "error", err, "request_id", requestID)
+		}
+	}
+
+	return true
+}
+
+// respond tells the session what went wrong with a message that never made
+// it to a handler.
+func (s *service) respond(session *melody.Session, requestID string, err error) {
+	s.sendResponse(session, requestID, responseEvent(requestID, err))
+}
+
+// respondTo acknowledges a handled request or tells the session what went
+// wrong with it, and remembers the answer for retries of the request.
+func (s *service) respondTo(session *melody.Session, requests *requestLog, requestID string, err error) {
+	event := responseEvent(requestID, err)
+	if requestID != "" {
+		requests.finish(requestID, event)
+	}
+
+	s.sendResponse(session, requestID, event)
+}
+
+// responseEvent is the answer to a request. Successful requests without an
+// id are not acknowledged.
+func responseEvent(requestID string, err error) any {
+	switch {
+	case err != nil:
+		code, message := errorCode(err)
+		return models.ErrorEvent{
+			Type: models.EventTypeError,
+			EventData: models.ErrorDetails{
+				Code:      code,
+				Message:   message,
+				RequestID: requestID,
+			},
+		}
+	case requestID != "":
+		return models.AckEvent{
+			Type: models.EventTypeAck,
+			EventData: models.Ack{
+				RequestID: requestID,
+			},
+		}
+	}
+
+	return nil
+}
+
+func (s *service) sendResponse(session *melody.Session, requestID string, event any) {
+	if event == nil {
+		return
+	}
+
+	if err := s.write(session, event); err != nil {
+		s.log.ErrorContext(session.Request.Context(), "failed to send response", "error", err, "request_id", requestID)
+	}
+}
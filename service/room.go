@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Jubris-Knifes/wgj25-back/config"
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/olahol/melody"
+)
+
+// room holds the codes players need to join, one per hub that created a
+// room, and the public address the server is reachable at. A hub creating a
+// new room only replaces its own code, links other hubs handed out stay good.
+// Codes outlive the hub that created them, so a hub dropping its connection
+// doesn't lock out the players it showed the code to.
+type room struct {
+	mu    sync.Mutex
+	codes map[*melody.Session]string
+	// latest is the hub that created a room last, whose code the server
+	// shows on its own screen.
+	latest   *melody.Session
+	endpoint string
+	onChange func(joinURL, code string)
+}
+
+func newRoomCode(codes map[*melody.Session]string) string {
+	for {
+		code := make([]byte, models.RoomCodeLength)
+		for i := range code {
+			code[i] = models.RoomCodeAlphabet[rand.IntN(len(models.RoomCodeAlphabet))]
+		}
+
+		if !roomCodeTaken(codes, string(code)) {
+			return string(code)
+		}
+	}
+}
+
+func roomCodeTaken(codes map[*melody.Session]string, code string) bool {
+	for _, taken := range codes {
+		if taken == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// joinURL points players at the frontend with the room code filled in, and
+// the websocket address of the server when it is known. It is empty while
+// no frontend is configured, there is nothing else to open.
+func joinURL(frontend, endpoint, code string) string {
+	u, err := url.Parse(frontend)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	query := u.Query()
+	if endpoint != "" {
+		query.Set("server", endpoint)
+	}
+	if code != "" {
+		query.Set("room", code)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// SetFrontendEndpoint records the public address the server is reachable at,
+// once the share is up.
+func (s *service) SetFrontendEndpoint(endpoint string) {
+	s.room.mu.Lock()
+	s.room.endpoint = endpoint
+	rooms := make(map[*melody.Session]models.Room, len(s.room.codes))
+	for hub, code := range s.room.codes {
+		rooms[hub] = s.roomOf(code)
+	}
+	s.room.mu.Unlock()
+
+	for hub, room := range rooms {
+		s.sendRoom(context.Background(), hub, room)
+	}
+
+	s.roomChanged()
+}
+
+// OnRoomChange registers fn to be called with the join URL and room code
+// whenever they change, see JoinURL.
+func (s *service) OnRoomChange(fn func(joinURL, code string)) {
+	s.room.mu.Lock()
+	defer s.room.mu.Unlock()
+
+	s.room.onChange = fn
+}
+
+func (s *service) roomChanged() {
+	s.room.mu.Lock()
+	onChange := s.room.onChange
+	s.room.mu.Unlock()
+
+	if onChange != nil {
+		onChange(s.JoinURL(), s.RoomCode())
+	}
+}
+
+// JoinURL is the address players join the table at, with the code of the
+// room created last if any.
+func (s *service) JoinURL() string {
+	s.room.mu.Lock()
+	defer s.room.mu.Unlock()
+
+	return joinURL(config.Get().Join.FrontendURL, s.room.endpoint, s.room.codes[s.room.latest])
+}
+
+// RoomCode is the code of the room created last, if any.
+func (s *service) RoomCode() string {
+	s.room.mu.Lock()
+	defer s.room.mu.Unlock()
+
+	return s.room.codes[s.room.latest]
+}
+
+// roomOf is called with room.mu held.
+func (s *service) roomOf(code string) models.Room {
+	return models.Room{
+		Code:    code,
+		JoinURL: joinURL(config.Get().Join.FrontendURL, s.room.endpoint, code),
+	}
+}
+
+// hubRoom returns the room the hub created, if it did.
+func (s *service) hubRoom(hub *melody.Session) (models.Room, bool) {
+	s.room.mu.Lock()
+	defer s.room.mu.Unlock()
+
+	code, ok := s.room.codes[hub]
+	if !ok {
+		return models.Room{}, false
+	}
+
+	return s.roomOf(code), true
+}
+
+// checkRoomCode lets players in with the code of any open room, or with
+// anything while no hub has created one.
+func (s *service) checkRoomCode(code string) error {
+	s.room.mu.Lock()
+	defer s.room.mu.Unlock()
+
+	if len(s.room.codes) > 0 && !roomCodeTaken(s.room.codes, strings.ToUpper(strings.TrimSpace(code))) {
+		return ErrUnknownRoom
+	}
+
+	return nil
+}
+
+func (s *service) handleCreateRoomEvent(session *melody.Session, _ models.CreateRoom) error {
+	ctx := session.Request.Context()
+
+	s.room.mu.Lock()
+	if s.room.codes == nil {
+		s.room.codes = make(map[*melody.Session]string)
+	}
+	code := newRoomCode(s.room.codes)
+	s.room.codes[session] = code
+	s.room.latest = session
+	room := s.roomOf(code)
+	s.room.mu.Unlock()
+
+	s.log.InfoContext(ctx, "room created", "code", code, "remote_address", session.RemoteAddr().String())
+	s.sendRoom(ctx, session, room)
+	s.roomChanged()
+
+	return nil
+}
+
+func (s *service) sendRoom(ctx context.Context, hub *melody.Session, room models.Room) {
+	event := models.RoomEvent{
+		Type:      models.EventTypeRoom,
+		EventData: room,
+	}
+
+	if err := s.write(hub, event); err != nil {
+		s.log.ErrorContext(ctx, "failed to send room event", "error", err)
+	}
+}
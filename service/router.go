@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/olahol/melody"
+)
+
+var (
+	eventsHandled  = expvar.NewMap("events_handled")
+	eventsFailed   = expvar.NewMap("events_failed")
+	eventsDuration = expvar.NewMap("events_duration_microseconds")
+)
+
+type (
+	handlerFunc func(session *melody.Session, envelope models.EnvelopeIn) error
+
+	// middleware wraps the handler of every route.
+	middleware func(eventType models.EventType, next handlerFunc) handlerFunc
+
+	// validator is implemented by payloads that can check themselves once
+	// decoded.
+	validator interface {
+		Validate() error
+	}
+
+	route struct {
+		handler handlerFunc
+		roles   []models.Role
+		phases  []models.Phase
+	}
+
+	routeOption func(*route)
+
+	router struct {
+		routes     map[models.EventType]*route
+		middleware []middleware
+		phase      func() models.Phase
+	}
+)
+
+func newRouter(phase func() models.Phase, middleware ...middleware) *router {
+	return &router{
+		routes:     map[models.EventType]*route{},
+		middleware: middleware,
+		phase:      phase,
+	}
+}
+
+// forRoles only lets sessions with one of the given roles send the event.
+func forRoles(roles ...models.Role) routeOption {
+	return func(r *route) {
+		r.roles = roles
+	}
+}
+
+// duringPhases only accepts the event while the game is in one of the given
+// phases.
+func duringPhases(phases ...models.Phase) routeOption {
+	return func(r *route) {
+		r.phases = phases
+	}
+}
+
+// on registers the handler of an event type. The payload is decoded with the
+// session's codec and validated before handle is called.
+func on[T any](r *router, eventType models.EventType, handle func(session *melody.Session, data T) error, options ...routeOption) {
+	if _, ok := r.routes[eventType]; ok {
+		panic(fmt.Sprintf("handler for %s already registered", eventType))
+	}
+
+	decodeAndHandle := func(session *melody.Session, envelope models.EnvelopeIn) error {
+		var data T
+		if len(envelope.EventData) > 0 {
+			if err := sessionCodec(session).Unmarshal(envelope.EventData, &data); err != nil {
+				return newClientError(models.ErrorCodeInvalidPayload, fmt.Sprintf("invalid %s payload", eventType))
+			}
+		}
+
+		if v, ok := any(data).(validator); ok {
+			if err := v.Validate(); err != nil {
+				return newClientError(models.ErrorCodeInvalidPayload, err.Error())
+			}
+		}
+
+		return handle(session, data)
+	}
+
+	rt := &route{handler: decodeAndHandle}
+	for _, option := range options {
+		option(rt)
+	}
+
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		rt.handler = r.middleware[i](eventType, rt.handler)
+	}
+
+	r.routes[eventType] = rt
+}
+
+func (r *router) dispatch(session *melody.Session, envelope models.EnvelopeIn) error {
+	rt, ok := r.routes[envelope.Type]
+	if !ok {
+		return newClientError(models.ErrorCodeUnknownEvent, fmt.Sprintf("unknown event type %q", envelope.Type))
+	}
+
+	if len(rt.roles) > 0 && !slices.Contains(rt.roles, sessionRole(session)) {
+		return newClientError(models.ErrorCodeNotAllowed,
+			fmt.Sprintf("%s can't send %s", sessionRole(session), envelope.Type))
+	}
+
+	if len(rt.phases) > 0 {
+		if phase := r.phase(); !slices.Contains(rt.phases, phase) {
+			return newClientError(models.ErrorCodeWrongPhase,
+				fmt.Sprintf("%s not accepted during %s", envelope.Type, phase))
+		}
+	}
+
+	return rt.handler(session, envelope)
+}
+
+func (s *service) logEvents(eventType models.EventType, next handlerFunc) handlerFunc {
+	return func(session *melody.Session, envelope models.EnvelopeIn) error {
+		ctx := session.Request.Context()
+
+		s.log.DebugContext(ctx, "handling event",
+			"type", eventType,
+			"request_id", envelope.RequestID,
+			"remote_address", session.RemoteAddr().String(),
+		)
+
+		err := next(session, envelope)
+
+		var clientErr *clientError
+		switch {
+		case errors.As(err, &clientErr):
+			s.log.WarnContext(ctx, "event rejected", "type", eventType, "error", err)
+		case err != nil:
+			s.log.ErrorContext(ctx, "failed to handle event", "type", eventType, "error", err)
+		}
+
+		return err
+	}
+}
+
+func measureEvents(eventType models.EventType, next handlerFunc) handlerFunc {
+	return func(session *melody.Session, envelope models.EnvelopeIn) error {
+		start := time.Now()
+		err := next(session, envelope)
+
+		eventsHandled.Add(string(eventType), 1)
+		eventsDuration.Add(string(eventType), time.Since(start).Microseconds())
+		if err != nil {
+			eventsFailed.Add(string(eventType), 1)
+		}
+
+		return err
+	}
+}
+
+func (s *service) registerRoutes() {
+	r := s.router
+
+	on(r, models.EventTypeHello, s.handleHelloEvent)
+	on(r, models.EventTypePing, s.handlePingEvent)
+	on(r, models.EventTypeSetName, s.handleSetNameEvent,
+		forRoles(models.RolePlayer), duringPhases(models.PhaseLobby))
+	on(r, models.EventTypeResume, s.handleResumeEvent,
+		forRoles(models.RolePlayer))
+	on(r, models.EventTypeSetReady, s.handleSetReadyEvent,
+		forRoles(models.RolePlayer), duringPhases(models.PhaseLobby))
+	on(r, models.EventTypeStartGame, s.handleStartGameEvent,
+		forRoles(models.RolePlayer, models.RoleHub), duringPhases(models.PhaseLobby))
+	on(r, models.EventTypeSettingsUpdate, s.handleSettingsUpdateEvent,
+		forRoles(models.RolePlayer, models.RoleHub), duringPhases(models.PhaseLobby))
+	on(r, models.EventTypeCreateRoom, s.handleCreateRoomEvent,
+		forRoles(models.RoleHub), duringPhases(models.PhaseLobby))
+	on(r, models.EventTypeBidSelected, s.handleBidSelectedEvent,
+		forRoles(models.RolePlayer), duringPhases(models.PhaseBid))
+	on(r, models.EventTypeOfferSelected, s.handleOfferSelectedEvent,
+		forRoles(models.RolePlayer), duringPhases(models.PhaseOffers))
+	on(r, models.EventTypePlayerChooseOffer, s.handlePlayerChooseOfferEvent,
+		forRoles(models.RolePlayer), duringPhases(models.PhaseChooseOffer))
+	on(r, models.EventTypePauseGame, s.handlePauseGameEvent,
+		forRoles(models.RoleHub))
+	on(r, models.EventTypeResumeGame, s.handleResumeGameEvent,
+		forRoles(models.RoleHub))
+}
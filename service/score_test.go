@@ -0,0 +1,111 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+)
+
+var testPoints = models.PointSettings{
+	FakePoker:    8000,
+	Poker:        5000,
+	OneOfEach:    4000,
+	FullHouse:    3500,
+	ThreeOfAKind: 3000,
+	TwoPair:      2500,
+	Pair:         2000,
+	FakeOne:      -250,
+	FakeTwo:      -1000,
+	FakeThree:    -2500,
+}
+
+func realCard(cardType, id int) models.Card {
+	return models.Card{ID: id, Type: cardType, IsReal: true}
+}
+
+func fakeCard(cardType int) models.Card {
+	return models.Card{ID: 1, Type: cardType}
+}
+
+func TestCalculateRoundPointsClassic(t *testing.T) {
+	tests := []struct {
+		name string
+		hand []models.Card
+		want int
+	}{
+		{"fake poker", []models.Card{fakeCard(1), fakeCard(2), fakeCard(3), fakeCard(4), realCard(1, 1)}, 8000},
+		{"poker", []models.Card{realCard(1, 1), realCard(1, 2), realCard(1, 3), realCard(1, 4), realCard(2, 1)}, 5000},
+		{"one of each", []models.Card{realCard(1, 1), realCard(2, 1), realCard(3, 1), realCard(4, 1), realCard(4, 2)}, 4000},
+		{"full house", []models.Card{realCard(1, 1), realCard(1, 2), realCard(1, 3), realCard(2, 1), realCard(2, 2)}, 3500},
+		{"three of a kind", []models.Card{realCard(1, 1), realCard(1, 2), realCard(1, 3), realCard(2, 1), realCard(3, 1)}, 3000},
+		{"two pair", []models.Card{realCard(1, 1), realCard(1, 2), realCard(2, 1), realCard(2, 2), realCard(3, 1)}, 2500},
+		{"two pair with a fake", []models.Card{realCard(1, 1), realCard(1, 2), realCard(2, 1), realCard(3, 1), fakeCard(2)}, 2500 - 250},
+		{"full house with two fakes", []models.Card{realCard(1, 1), realCard(1, 2), fakeCard(1), realCard(2, 1), fakeCard(2)}, 3500 - 1000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := calculateRoundPoints(testPoints, test.hand); got != test.want {
+				t.Errorf("calculateRoundPoints(%v) = %d, want %d", test.hand, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCalculateRoundPointsNoFakes(t *testing.T) {
+	tests := []struct {
+		name string
+		hand []models.Card
+		want int
+	}{
+		{"poker", []models.Card{realCard(1, 1), realCard(1, 2), realCard(1, 3), realCard(1, 4)}, 5000},
+		{"one of each", []models.Card{realCard(1, 1), realCard(2, 1), realCard(3, 1), realCard(4, 1)}, 4000},
+		{"three of a kind", []models.Card{realCard(1, 1), realCard(1, 2), realCard(1, 3), realCard(2, 1)}, 3000},
+		{"two pair is not a full house", []models.Card{realCard(1, 1), realCard(1, 2), realCard(2, 1), realCard(2, 2)}, 2500},
+		{"pair", []models.Card{realCard(1, 1), realCard(1, 2), realCard(2, 1), realCard(3, 1)}, 2000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := calculateRoundPoints(testPoints, test.hand); got != test.want {
+				t.Errorf("calculateRoundPoints(%v) = %d, want %d", test.hand, got, test.want)
+			}
+		})
+	}
+}
+
+func TestShuffleAndGiveCardsToPlayers(t *testing.T) {
+	tests := []struct {
+		ruleset  models.Ruleset
+		handSize int
+	}{
+		{models.RulesetClassic, 5},
+		{models.RulesetNoFakes, 4},
+	}
+
+	playerIDs := []int{1, 2, 3, 4}
+	for _, test := range tests {
+		t.Run(string(test.ruleset), func(t *testing.T) {
+			hands := shuffleAndGiveCardsToPlayers(playerIDs, test.ruleset)
+
+			seen := map[models.Card]bool{}
+			for _, playerID := range playerIDs {
+				hand := hands[playerID]
+				if len(hand) != test.handSize {
+					t.Errorf("player %d got %d cards, want %d", playerID, len(hand), test.handSize)
+				}
+
+				for _, card := range hand {
+					if seen[card] {
+						t.Errorf("card %v dealt twice", card)
+					}
+					seen[card] = true
+
+					if test.ruleset == models.RulesetNoFakes && !card.IsReal {
+						t.Errorf("fake card %v dealt with the %s ruleset", card, test.ruleset)
+					}
+				}
+			}
+		})
+	}
+}
@@ -2,46 +2,96 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"math/rand/v2"
 	"slices"
+	"sync"
 	"time"
 
-	"github.com/Jubris-Knifes/wgj25-back/config"
 	"github.com/Jubris-Knifes/wgj25-back/models"
 	"github.com/Jubris-Knifes/wgj25-back/repository"
+	"github.com/Jubris-Knifes/wgj25-back/transport"
 	"github.com/olahol/melody"
 	"golang.org/x/sync/errgroup"
 )
 
 const (
-	PlayerIDKey = "player_id"
+	PlayerIDKey        = transport.PlayerIDKey
+	ProtocolVersionKey = "protocol_version"
+	RoleKey            = transport.RoleKey
 )
 
-var (
-	bidSelectedChan                = make(chan models.BidSelected, 1)
-	offerSelectedChan              = make(chan models.PlayerOffer, 3)
-	currentPlayerSelectedOfferChan = make(chan int, 1)
+const (
+	closeCodeKicked      = 4000
+	closeCodeRejected    = 4001
+	closeCodeRateLimited = 4002
 )
 
 type service struct {
 	repo *repository.Repository
 	log  *slog.Logger
-	m    *melody.Melody
+
+	sessions  *transport.Registry
+	transport transport.Transport
+
+	router *router
+
+	gameMu       sync.Mutex
+	gameCancel   context.CancelFunc
+	phase        models.Phase
+	step         models.ResumeStep
+	roundsPlayed int
+	seats        []int
+	restored     *models.GameState
+	timeLeft     *phaseTimeLeft
+	saveMu       sync.Mutex
+	clock        *phaseClock
+
+	turn     turnState
+	settings models.Settings
+	lobby    lobby
+	requests requestLogs
+	room     room
+	afk      *afkTracker
+	bot      strategy
+	autoPick strategy
+
+	bids         *phaseInput[models.BidSelected]
+	offers       *phaseInput[models.PlayerOffer]
+	chosenOffers *phaseInput[int]
 }
 
-func New(logger *slog.Logger, repo *repository.Repository, m *melody.Melody) *service {
-	return &service{
-		repo: repo,
-		log:  logger,
-		m:    m,
+func New(logger *slog.Logger, repo *repository.Repository, sessions *transport.Registry, t transport.Transport) (*service, error) {
+	settings := defaultSettings()
+	if err := settings.Validate(); err != nil {
+		return nil, fmt.Errorf("configured table settings: %w", err)
+	}
+
+	s := &service{
+		repo:         repo,
+		log:          logger,
+		sessions:     sessions,
+		transport:    t,
+		phase:        models.PhaseLobby,
+		clock:        newPhaseClock(),
+		afk:          newAFKTracker(),
+		settings:     settings,
+		bids:         newPhaseInput[models.BidSelected]("bid"),
+		offers:       newPhaseInput[models.PlayerOffer]("offer"),
+		chosenOffers: newPhaseInput[int]("offer choice"),
 	}
 
+	s.router = newRouter(s.currentPhase, s.logEvents, measureEvents)
+	s.registerRoutes()
+
+	go s.monitorConnections()
+
+	return s, nil
 }
 
 func (s *service) NewConnection(session *melody.Session) {
+	s.sessions.Add(session)
 	s.log.InfoContext(session.Request.Context(),
 		"New conncetion established",
 		"remote_address",
@@ -49,35 +99,6 @@ func (s *service) NewConnection(session *melody.Session) {
 	)
 }
 
-func (s *service) hasNextRound() bool {
-	//TODO: when does game end?
-	return true
-}
-
-func (s *service) endGame() {
-	// TODO: define end game loop
-	panic("unimplemented")
-}
-
-func (s *service) broadcastToHub(data any) error {
-	payload, err := json.Marshal(data)
-	if err != nil {
-		s.log.ErrorContext(context.Background(), "failed to marshal broadcast payload", "error", err)
-		return err
-	}
-
-	err = s.m.BroadcastFilter(payload, func(session *melody.Session) bool {
-		_, ok := getAs[int](s.log, session, PlayerIDKey)
-		return !ok
-	})
-	if err != nil {
-		s.log.ErrorContext(context.Background(), "failed to broadcast to hub", "error", err)
-		return err
-	}
-
-	return nil
-}
-
 func getAs[T any](log *slog.Logger, s *melody.Session, key string) (T, bool) {
 	value, ok := s.Get(key)
 	if !ok {
@@ -95,100 +116,164 @@ func getAs[T any](log *slog.Logger, s *melody.Session, key string) (T, bool) {
 
 func (s *service) ClosedConnection(session *melody.Session) {
 	ctx := session.Request.Context()
-	id, ok := getAs[int](s.log, session, PlayerIDKey)
-	if !ok {
-		s.log.ErrorContext(ctx, "failed to get player ID from session", "session_id", session.RemoteAddr().String())
+	_, isPlayer := transport.SessionPlayerID(session)
+	id, stillConnected := s.sessions.Remove(session)
+	if !isPlayer {
+		s.log.DebugContext(ctx, "closed session had no player", "remote_address", session.RemoteAddr().String())
+		return
+	}
+
+	if stillConnected {
+		s.log.DebugContext(ctx, "player still has other sessions open", "player_id", id)
 		return
 	}
 
 	if err := s.repo.ClosePlayer(ctx, id); err != nil {
 		s.log.ErrorContext(ctx, "failed to close player", "error", err, "player_id", id)
 	}
+
+	s.playerLeft(ctx, id)
 }
 
+// HandleMessage handles text frames, which are only valid for sessions
+// speaking JSON.
 func (s *service) HandleMessage(session *melody.Session, msg []byte) {
+	if !s.allowMessage(session) {
+		return
+	}
+
+	if sessionCodec(session).Binary() {
+		s.respond(session, "", newClientError(models.ErrorCodeInvalidEncoding,
+			"session negotiated "+sessionCodec(session).Name()+", send binary frames"))
+		return
+	}
+
 	var envelope models.EnvelopeIn
-	if err := json.Unmarshal(msg, &envelope); err != nil {
+	if err := s.decode(session, msg, &envelope); err != nil {
 		s.log.ErrorContext(session.Request.Context(), "failed to unmarshal message", "error", err)
+		s.respond(session, "", newClientError(models.ErrorCodeInvalidJSON, "message is not valid JSON"))
 		return
 	}
 
-	// Handle the message based on its type
-	switch envelope.Type {
-	case models.EventTypeSetName:
-		s.handleSetNameEvent(session, envelope.EventData)
-	case models.EventTypeBidSelected:
-		s.handleBidSelectedEvent(session, envelope.EventData)
-	case models.EventTypeOfferSelected:
-		s.handleOfferSelectedEvent(session, msg)
-	case models.EventTypePlayerChooseOffer:
-		s.handlePlayerChooseOfferEvent(session, envelope.EventData)
-	default:
-		s.log.WarnContext(session.Request.Context(), "unknown message type", "type", envelope.Type)
+	s.handleEnvelope(session, envelope)
+}
+
+// HandleMessageBinary handles binary frames, encoded with the binary codec
+// negotiated at handshake.
+func (s *service) HandleMessageBinary(session *melody.Session, msg []byte) {
+	if !s.allowMessage(session) {
+		return
 	}
+
+	c := sessionCodec(session)
+	if !c.Binary() {
+		s.respond(session, "", newClientError(models.ErrorCodeInvalidEncoding, "no binary encoding negotiated"))
+		return
+	}
+
+	var envelope models.EnvelopeIn
+	if err := s.decode(session, msg, &envelope); err != nil {
+		s.log.ErrorContext(session.Request.Context(), "failed to unmarshal binary message", "error", err, "codec", c.Name())
+		s.respond(session, "", newClientError(models.ErrorCodeInvalidEncoding, "message is not valid "+c.Name()))
+		return
+	}
+
+	s.handleEnvelope(session, envelope)
 }
 
-func (s *service) handlePlayerChooseOfferEvent(session *melody.Session, eventData json.RawMessage) {
-	var playerChooseOffer models.PlayerChooseOffer
-	if err := json.Unmarshal(eventData, &playerChooseOffer); err != nil {
-		s.log.ErrorContext(session.Request.Context(), "failed to unmarshal player_choose_offer event", "error", err)
+func (s *service) handleEnvelope(session *melody.Session, envelope models.EnvelopeIn) {
+	if _, ok := getAs[int](s.log, session, ProtocolVersionKey); !ok && envelope.Type != models.EventTypeHello {
+		s.rejectHandshake(session, models.Hello{}, "hello handshake required")
 		return
 	}
 
+	// A set_name or resume gives the session a player, its answer still
+	// belongs with the log the request started in.
+	requests := s.requestLog(session)
+	if s.replayResponse(session, requests, envelope.RequestID) {
+		return
+	}
+
+	if playerID, ok := transport.SessionPlayerID(session); ok && envelope.Type != models.EventTypePing {
+		s.playerActive(session.Request.Context(), playerID)
+	}
+
+	err := s.router.dispatch(session, envelope)
+
+	s.respondTo(session, requests, envelope.RequestID, err)
+}
+
+func (s *service) handlePauseGameEvent(session *melody.Session, _ models.PauseGame) error {
+	return s.pauseGame(session.Request.Context())
+}
+
+func (s *service) handleResumeGameEvent(session *melody.Session, _ models.ResumeGame) error {
+	return s.resumeGame(session.Request.Context())
+}
+
+func (s *service) handlePlayerChooseOfferEvent(session *melody.Session, playerChooseOffer models.PlayerChooseOffer) error {
 	// Process the player choose offer event
 	s.log.DebugContext(session.Request.Context(), "player_choose_offer event received", "offer", playerChooseOffer)
 
-	currentPlayerSelectedOfferChan <- playerChooseOffer.PlayerID
+	playerID, ok := getAs[int](s.log, session, PlayerIDKey)
+	if !ok {
+		return newClientError(models.ErrorCodeNotAllowed, "set a name before choosing an offer")
+	}
+
+	return s.chosenOffers.send(playerID, playerChooseOffer.PlayerID)
 }
 
-func (s *service) handleOfferSelectedEvent(session *melody.Session, msg []byte) {
+func (s *service) handleOfferSelectedEvent(session *melody.Session, offerSelected models.OfferSelected) error {
 	playerID, ok := getAs[int](s.log, session, PlayerIDKey)
 
 	if !ok {
 		s.log.Error("Player Id not present on session for selecting offer")
-		panic("session player id not found")
-	}
-
-	playerOffer := models.PlayerOffer{PlayerID: playerID}
-
-	if err := json.Unmarshal(msg, &playerOffer.Card); err != nil {
-		s.log.Error("failed to unmarshal player offer", "error", err)
-		panic(err)
+		return newClientError(models.ErrorCodeNotAllowed, "set a name before making an offer")
 	}
 
-	offerSelectedChan <- playerOffer
+	return s.offers.send(playerID, models.PlayerOffer{PlayerID: playerID, Card: offerSelected.Card})
 }
 
-func (s *service) handleBidSelectedEvent(session *melody.Session, eventData json.RawMessage) {
-	var bidSelected models.BidSelected
-	if err := json.Unmarshal(eventData, &bidSelected); err != nil {
-		s.log.ErrorContext(session.Request.Context(), "failed to unmarshal bid_selected event", "error", err)
-		return
+func (s *service) handleBidSelectedEvent(session *melody.Session, bidSelected models.BidSelected) error {
+	playerID, ok := getAs[int](s.log, session, PlayerIDKey)
+	if !ok {
+		return newClientError(models.ErrorCodeNotAllowed, "set a name before bidding")
 	}
 
-	bidSelectedChan <- bidSelected
-
+	return s.bids.send(playerID, bidSelected)
 }
 
-func (s *service) handleSetNameEvent(session *melody.Session, eventData json.RawMessage) {
+func (s *service) handleSetNameEvent(session *melody.Session, setName models.SetName) error {
 	ctx, cancel := context.WithTimeout(session.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	s.log.DebugContext(ctx, "handling set_name event", "event_data", string(eventData))
+	s.log.DebugContext(ctx, "handling set_name event", "name", setName.Name)
 
-	var setName models.SetName
-	if err := json.Unmarshal(eventData, &setName); err != nil {
-		s.log.ErrorContext(session.Request.Context(), "failed to unmarshal set_name event", "error", err)
-		return
+	if playerID, ok := transport.SessionPlayerID(session); ok {
+		return newClientError(models.ErrorCodeNotAllowed, fmt.Sprintf("already at the table as player %d", playerID))
+	}
+
+	if err := s.checkRoomCode(setName.RoomCode); err != nil {
+		return err
 	}
 
-	playerID, err := s.repo.NewPlayer(ctx, setName.Name)
+	playerID, err := s.repo.NewPlayer(ctx, setName.Name, s.rules().TableSize)
 	if err != nil {
 		s.log.ErrorContext(ctx, "failed to create new player", "error", err)
-		return
+		return err
 	}
 
-	session.Set(PlayerIDKey, playerID)
+	s.sessions.SetPlayer(session, playerID)
+
+	resumeToken, err := newResumeToken()
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to generate resume token", "error", err)
+		return err
+	}
+
+	if err := s.repo.SetResumeToken(ctx, playerID, resumeToken); err != nil {
+		return err
+	}
 
 	s.log.DebugContext(ctx, "set_name event processed",
 		"player_id", playerID,
@@ -198,18 +283,13 @@ func (s *service) handleSetNameEvent(session *melody.Session, eventData json.Raw
 		Type: models.EventTypeSetNameResponse,
 		EventData: models.SetNameResponse{
 			AssignedPlayerID: playerID,
+			ResumeToken:      resumeToken,
 		},
 	}
 
 	errGroup := &errgroup.Group{}
 	errGroup.Go(func() error {
-		payload, err := json.Marshal(response)
-		if err != nil {
-			s.log.ErrorContext(ctx, "failed to marshal response", "error", err)
-			return err
-		}
-
-		session.Write(payload)
+		s.write(session, response)
 
 		s.log.DebugContext(ctx, "set_name response sent",
 			"player_id", playerID,
@@ -228,12 +308,7 @@ func (s *service) handleSetNameEvent(session *melody.Session, eventData json.Raw
 			},
 		}
 
-		payload, err := json.Marshal(response)
-		if err != nil {
-			return err
-		}
-
-		if err := s.m.BroadcastOthers(payload, session); err != nil {
+		if err := s.broadcastOthers(response, session); err != nil {
 			s.log.ErrorContext(ctx, "failed to broadcast player joined", "error", err)
 			return err
 		}
@@ -246,41 +321,94 @@ func (s *service) handleSetNameEvent(session *melody.Session, eventData json.Raw
 
 	errGroup.Wait()
 
-	if count, err := s.repo.GetActivePlayerCount(ctx); err != nil {
-		s.log.ErrorContext(ctx, "failed to get active player count", "error", err)
-	} else if count == 4 {
-		go s.startRound()
+	if err := s.sendStateSnapshot(ctx, session); err != nil {
+		s.log.ErrorContext(ctx, "failed to send state snapshot", "error", err)
 	}
+
+	s.lobby.join(playerID)
+	s.broadcastRoster(ctx)
+
 	// Process the set_name event
 	s.log.InfoContext(session.Request.Context(), "set_name event received", "player_id", playerID, "name", setName.Name)
+
+	return nil
 }
 
-func (s *service) startRound() {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// handleResumeEvent gives a player their seat back. It's how players of a
+// game restored after a restart get back into it.
+func (s *service) handleResumeEvent(session *melody.Session, resume models.Resume) error {
+	ctx, cancel := context.WithTimeout(session.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	player, err := s.repo.ResumePlayer(ctx, resume.ResumeToken)
+	if err != nil {
+		return err
+	}
+
+	s.sessions.SetPlayer(session, player.PlayerID)
+
+	s.log.InfoContext(ctx, "player resumed", "player_id", player.PlayerID, "name", player.Name)
+
+	response := models.SetNameResponseEvent{
+		Type: models.EventTypeSetNameResponse,
+		EventData: models.SetNameResponse{
+			AssignedPlayerID: player.PlayerID,
+			ResumeToken:      resume.ResumeToken,
+		},
+	}
+	if err := s.write(session, response); err != nil {
+		s.log.ErrorContext(ctx, "failed to send set_name_response event", "error", err)
+	}
+
+	joined := models.PlayerJoinedEvent{
+		Type: models.EventTypePlayerJoined,
+		EventData: models.PlayerJoined{
+			PlayerID: player.PlayerID,
+			Name:     player.Name,
+		},
+	}
+	if err := s.broadcastOthers(joined, session); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast player joined", "error", err)
+	}
+
+	if err := s.sendStateSnapshot(ctx, session); err != nil {
+		s.log.ErrorContext(ctx, "failed to send state snapshot", "error", err)
+	}
+
+	s.lobby.join(player.PlayerID)
+	s.broadcastRoster(ctx)
+	s.resumeRestoredGame(ctx)
+
+	return nil
+}
+
+func (s *service) startRound(gameCtx context.Context) error {
+	ctx, cancel := context.WithTimeout(gameCtx, 10*time.Second)
 	defer cancel()
 	s.log.Info("Starting a new round")
-	playerIDs, err := s.repo.GetActivePlayerIDs(ctx)
+	s.setPhase(gameCtx, models.PhaseDealing)
+	s.turn.reset()
+	playerIDs, err := s.seatPlayers(ctx)
 	if err != nil {
-		s.log.ErrorContext(ctx, "failed to get active player IDs", "error", err)
-		return
+		s.log.ErrorContext(ctx, "failed to seat players", "error", err)
+		return err
 	}
 
-	s.repo.DropPlayerHands(ctx)
+	if err := s.repo.DropPlayerHands(ctx); err != nil {
+		return err
+	}
 
 	dealingCardsEvent := models.DealingCardsEvent{
 		Type:      models.EventTypeDealingCards,
 		EventData: models.DealingCards{},
 	}
 
-	payload, err := json.Marshal(dealingCardsEvent)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to marshal dealing_cards event", "error", err)
-		return
+	if err := s.publish(ctx, everyone(dealingCardsEvent)); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast dealing cards event", "error", err)
+		return err
 	}
 
-	s.m.Broadcast(payload)
-
-	playerCards := shuffleAndGiveCardsToPlayers(playerIDs)
+	playerCards := shuffleAndGiveCardsToPlayers(playerIDs, s.rules().Ruleset)
 
 	errGroup := &errgroup.Group{}
 	for playerID, cards := range playerCards {
@@ -296,55 +424,63 @@ func (s *service) startRound() {
 				return err
 			}
 
-			payload, err := json.Marshal(cardsDealtEvent)
-			if err != nil {
-				s.log.ErrorContext(context.Background(), "failed to marshal cards_dealt event", "error", err)
-				return err
-			}
-
-			s.m.BroadcastFilter(payload, func(session *melody.Session) bool {
-				pID, _ := getAs[int](s.log, session, PlayerIDKey)
-				return pID == playerID
-			})
-			return nil
+			return s.publish(ctx, toPlayer(playerID, cardsDealtEvent))
 		})
 	}
-	errGroup.Wait()
+	if err := errGroup.Wait(); err != nil {
+		return err
+	}
+
+	if err := gameCtx.Err(); err != nil {
+		return err
+	}
+
+	startingPlayer := rand.IntN(len(playerIDs))
+	if err := s.repo.SetCurrentPlayerID(ctx, playerIDs[startingPlayer]); err != nil {
+		return err
+	}
 
-	startingPlayer := rand.IntN(4)
-	s.repo.SetCurrentPlayerID(ctx, playerIDs[startingPlayer])
-	s.startTurn()
+	return s.checkpoint(gameCtx, models.ResumeTurn)
 }
 
-func (s *service) startTurn() {
-	s.startPlayerBid()
+// startTurn plays a turn, from the current player's bid to handing the turn
+// to the next player, unless the current player ends the round instead.
+func (s *service) startTurn(ctx context.Context) error {
+	return s.startPlayerBid(ctx)
 }
 
-func (s *service) endOfRound() {
-	ctx := context.Background()
+func (s *service) endOfRound(ctx context.Context) error {
 	s.log.InfoContext(ctx, "Ending round")
+	s.setPhase(ctx, models.PhaseEndOfRound)
 
-	scores := s.getUpdatedScoreBoard()
+	scores, err := s.getUpdatedScoreBoard(ctx)
+	if err != nil {
+		return err
+	}
 
-	timeout := time.Duration(config.Get().Timeouts.EndOfRoundScreen) * time.Millisecond
+	timeout := milliseconds(s.rules().Timeouts.EndOfRound)
 	endOfRoundEvent := models.EndOfRoundEvent{
 		Type: models.EventTypeEndOfRound,
 		EventData: models.EndOfRound{
-			Timeout: timeout.Milliseconds(),
+			Timeout:  timeout.Milliseconds(),
+			Deadline: deadlineIn(timeout),
 		},
 	}
 
-	if err := s.broadcastToHub(endOfRoundEvent); err != nil {
+	if err := s.publish(ctx, toTable(endOfRoundEvent)); err != nil {
 		s.log.ErrorContext(ctx, "failed to broadcast end of round event", "error", err)
-		panic(err)
+		return err
+	}
+	if err := s.wait(ctx, timeout); err != nil {
+		return err
 	}
-	time.Sleep(timeout)
 
-	updateScoreTimeout := time.Duration(config.Get().Timeouts.UpdateScoreScreen) * time.Millisecond
+	updateScoreTimeout := milliseconds(s.rules().Timeouts.UpdateScore)
 	updateScoreEvent := models.UpdateScoreEvent{
 		Type: models.EventTypeUpdateScore,
 		EventData: models.UpdateScore{
-			Timeout: updateScoreTimeout.Milliseconds(),
+			Timeout:  updateScoreTimeout.Milliseconds(),
+			Deadline: deadlineIn(updateScoreTimeout),
 		},
 	}
 
@@ -352,23 +488,26 @@ func (s *service) endOfRound() {
 		updateScoreEvent.EventData.Scores = append(updateScoreEvent.EventData.Scores, models.HandScore{
 			PlayerID: score.PlayerID,
 			Points:   score.RoundPoints,
-			Cards:    score.Hand,
+			Cards:    handFaces(score.Hand),
 		})
 	}
 
-	if err := s.broadcastToHub(updateScoreEvent); err != nil {
+	if err := s.publish(ctx, toTable(updateScoreEvent)); err != nil {
 		s.log.ErrorContext(ctx, "failed to broadcast update score event", "error", err)
-		panic(err)
+		return err
 	}
 
-	time.Sleep(updateScoreTimeout)
+	if err := s.wait(ctx, updateScoreTimeout); err != nil {
+		return err
+	}
 
-	sumScoreTimeout := time.Duration(config.Get().Timeouts.SumScore) * time.Millisecond
+	sumScoreTimeout := milliseconds(s.rules().Timeouts.SumScore)
 
 	sumScoreEvent := models.SumScoreEvent{
 		Type: models.EventTypeSumScore,
 		EventData: models.SumScore{
-			Timeout: sumScoreTimeout.Milliseconds(),
+			Timeout:  sumScoreTimeout.Milliseconds(),
+			Deadline: deadlineIn(sumScoreTimeout),
 		},
 	}
 
@@ -383,80 +522,136 @@ func (s *service) endOfRound() {
 		)
 	}
 
-	if err := s.broadcastToHub(sumScoreEvent); err != nil {
+	if err := s.publish(ctx, toTable(sumScoreEvent)); err != nil {
 		s.log.ErrorContext(ctx, "failed to broadcast sum score event", "error", err)
-		panic(err)
+		return err
 	}
 
-	time.Sleep(sumScoreTimeout)
+	newScores := make([]models.Score, 0, len(scores))
+	for _, score := range scores {
+		newScores = append(newScores, models.Score{PlayerID: score.PlayerID, Points: score.NewPoints})
+	}
 
-	if !s.hasNextRound() {
-		s.endGame()
+	if err := s.repo.SetPlayerScores(ctx, newScores); err != nil {
+		s.log.ErrorContext(ctx, "failed to save player scores", "error", err)
+		return err
 	}
 
-	prepareNextRoundTimeout := time.Duration(config.Get().Timeouts.PrepareForNextTurnMilliseconds) * time.Millisecond
+	s.gameMu.Lock()
+	s.roundsPlayed++
+	s.gameMu.Unlock()
+
+	if err := s.checkpoint(ctx, models.ResumeRound); err != nil {
+		return err
+	}
+
+	if err := s.wait(ctx, sumScoreTimeout); err != nil {
+		return err
+	}
+
+	if !s.hasNextRound(ctx) {
+		return nil
+	}
+
+	prepareNextRoundTimeout := milliseconds(s.rules().Timeouts.PrepareForNextTurn)
 
 	prepareNextRoundEvent := models.PrepareForNextTurnEvent{
 		Type: models.EventTypePrepareForNextTurn,
 		EventData: models.PrepareForNextTurn{
-			Timeout: prepareNextRoundTimeout.Milliseconds(),
+			Timeout:  prepareNextRoundTimeout.Milliseconds(),
+			Deadline: deadlineIn(prepareNextRoundTimeout),
 		},
 	}
 
-	payload, err := json.Marshal(prepareNextRoundEvent)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to marshal prepare next round event", "error", err)
-		panic(err)
-	}
-
-	if err := s.m.Broadcast(payload); err != nil {
+	if err := s.publish(ctx, everyone(prepareNextRoundEvent)); err != nil {
 		s.log.ErrorContext(ctx, "failed to broadcast prepare next round event", "error", err)
-		panic(err)
+		return err
 	}
 
-	time.Sleep(prepareNextRoundTimeout)
-
-	s.startRound()
+	return s.wait(ctx, prepareNextRoundTimeout)
 }
 
-func (s *service) startPlayerBid() {
-	ctx := context.Background()
+func (s *service) startPlayerBid(ctx context.Context) error {
+	s.turn.reset()
 
 	currentPlayerID, err := s.repo.GetCurrentPlayerID(ctx)
 	if err != nil {
 		s.log.ErrorContext(ctx, "failed to get current player ID", "error", err)
-		panic(err)
+		return err
 	}
 
-	timeoutForChoice := time.Duration(config.Get().Timeouts.PlayerChooseBidMilliseconds) * time.Millisecond
-	s.sendPlayerBidOfferEvent(ctx, currentPlayerID, timeoutForChoice)
-	ctx, cancel := context.WithTimeout(ctx, timeoutForChoice)
-	defer cancel()
-
 	currentPlayerHand, err := s.repo.GetPlayerHand(ctx, currentPlayerID)
 	if err != nil {
 		s.log.ErrorContext(ctx, "failed to get current player hand", "error", err)
-		panic(err)
+		return err
 	}
 
-	choice := currentPlayerHand[rand.IntN(len(currentPlayerHand))]
+	timeoutForChoice := milliseconds(s.rules().Timeouts.ChooseBid)
+	bids := s.bids.open([]int{currentPlayerID}, func(bid models.BidSelected) error {
+		if bid.IsRoundDone {
+			if !canFinishRound(currentPlayerHand) {
+				return newClientError(models.ErrorCodeInvalidPayload, "your hand can't finish the round")
+			}
+			return nil
+		}
+		return checkInHand(currentPlayerHand, bid.Card)
+	})
+	defer s.bids.close()
+	s.setPhase(ctx, models.PhaseBid)
+	if err := s.sendPlayerBidOfferEvent(ctx, currentPlayerID, currentPlayerHand, timeoutForChoice); err != nil {
+		return err
+	}
+
+	var choice models.Card
+	roundDone := false
+
+	// The bot plays for an AFK player after a short delay, unless they come
+	// back and bid before it does.
+	afk := s.afk.isAFK(currentPlayerID)
+	timeout := timeoutForChoice + s.latencyGrace(currentPlayerID)
+	if afk {
+		timeout = s.botDelay()
+	}
+	timer := s.startPhaseTimer(ctx, timeout)
 
 	select {
-	case playerChoice := <-bidSelectedChan:
-		if playerChoice.IsRoundDone {
-			s.endOfRound()
-			return
-		}
+	case playerChoice := <-bids:
+		s.afk.acted(currentPlayerID)
+		roundDone = playerChoice.IsRoundDone
 		choice = playerChoice.Card
-	case <-ctx.Done():
+	case <-timer.done:
+		if afk {
+			choice, roundDone = s.bot.bid(currentPlayerHand)
+			s.sendAutoAction(ctx, bidAction(currentPlayerID, models.AutoActionReasonAFK, choice, roundDone))
+			break
+		}
+		choice, roundDone = s.autoPick.bid(currentPlayerHand)
+		s.sendAutoAction(ctx, bidAction(currentPlayerID, models.AutoActionReasonTimeout, choice, roundDone))
+		s.playerTimedOut(ctx, currentPlayerID)
+	}
+	timer.stop()
+	s.bids.close()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if roundDone {
+		return s.checkpoint(ctx, models.ResumeEndOfRound)
 	}
 
-	s.sendPlayerBidWasSelectedEvent(choice, currentPlayerID)
+	s.turn.setBid(choice)
+
+	s.setPhase(ctx, models.PhaseShowBid)
+
+	if err := s.sendPlayerBidWasSelectedEvent(ctx, choice, currentPlayerID); err != nil {
+		return err
+	}
 
-	go s.startPlayersOffers(choice)
+	return s.startPlayersOffers(ctx, choice)
 }
 
-func calculateDiscountCausedByFakes(hand []models.Card) int {
+func calculateDiscountCausedByFakes(points models.PointSettings, hand []models.Card) int {
 	totalFakes := 0
 	for _, card := range hand {
 		if !card.IsReal {
@@ -466,18 +661,22 @@ func calculateDiscountCausedByFakes(hand []models.Card) int {
 
 	switch totalFakes {
 	case 1:
-		return config.Get().Points.FakeOne
+		return points.FakeOne
 	case 2:
-		return config.Get().Points.FakeTwo
+		return points.FakeTwo
 	case 3:
-		return config.Get().Points.FakeThree
-	case 4:
+		return points.FakeThree
+	case 0, 4:
 		return 0
 	}
 
 	panic("Unreachable code AHHHHHHHHH!!!!!!!!!!!!!!!!")
 }
 
+// cardKinds is the number of card types. Types start at 1, so slices indexed
+// by type need one more slot.
+const cardKinds = 4
+
 func isFakePoker(hand []models.Card) bool {
 	totalFakes := 0
 	for _, card := range hand {
@@ -490,11 +689,11 @@ func isFakePoker(hand []models.Card) bool {
 }
 
 func isPoker(hand []models.Card) bool {
-	kinds := make([]int, 4)
+	kinds := make([]int, cardKinds+1)
 
 	for _, card := range hand {
 		kinds[card.Type]++
-		if kinds[card.Type] == 4 {
+		if kinds[card.Type] == cardKinds {
 			return true
 		}
 	}
@@ -503,7 +702,7 @@ func isPoker(hand []models.Card) bool {
 }
 
 func isOneOfEach(hand []models.Card) bool {
-	kinds := make([]int, 4)
+	kinds := make([]int, cardKinds+1)
 	usedKinds := 0
 
 	for _, card := range hand {
@@ -513,26 +712,19 @@ func isOneOfEach(hand []models.Card) bool {
 		kinds[card.Type]++
 	}
 
-	return usedKinds == 4
+	return usedKinds == cardKinds
 }
 
+// isFullHouse needs three of a kind and a pair, so only five card hands can
+// make one. Two pairs in a four card hand are just that.
 func isFullHouse(hand []models.Card) bool {
-	kinds := make([]int, 4)
-	for _, card := range hand {
-		kinds[card.Type]++
-	}
-
-	for _, count := range kinds {
-		if count == 1 || count > 3 {
-			return false
-		}
-	}
+	kinds := countKinds(hand)
 
-	return true
+	return slices.Contains(kinds, 3) && slices.Contains(kinds, 2)
 }
 
 func isThreeOfAKind(hand []models.Card) bool {
-	kinds := make([]int, 4)
+	kinds := make([]int, cardKinds+1)
 	for _, card := range hand {
 		kinds[card.Type]++
 	}
@@ -548,7 +740,7 @@ func isThreeOfAKind(hand []models.Card) bool {
 }
 
 func isTwoPair(hand []models.Card) bool {
-	kinds := make([]int, 4)
+	kinds := make([]int, cardKinds+1)
 	for _, card := range hand {
 		kinds[card.Type]++
 	}
@@ -564,7 +756,7 @@ func isTwoPair(hand []models.Card) bool {
 }
 
 func isPair(hand []models.Card) bool {
-	kinds := make([]int, 4)
+	kinds := make([]int, cardKinds+1)
 	for _, card := range hand {
 		kinds[card.Type]++
 	}
@@ -579,35 +771,36 @@ func isPair(hand []models.Card) bool {
 	return usedKinds == 1
 }
 
-func calculateRoundPoints(hand []models.Card) int {
-	points := calculateDiscountCausedByFakes(hand)
+func calculateRoundPoints(points models.PointSettings, hand []models.Card) int {
+	total := calculateDiscountCausedByFakes(points, hand)
 
 	switch {
 	case isFakePoker(hand):
-		points += config.Get().Points.FakePoker
+		total += points.FakePoker
 	case isPoker(hand):
-		points += config.Get().Points.Poker
+		total += points.Poker
 	case isOneOfEach(hand):
-		points += config.Get().Points.OneOfEach
+		total += points.OneOfEach
 	case isFullHouse(hand):
-		points += config.Get().Points.FullHouse
+		total += points.FullHouse
 	case isThreeOfAKind(hand):
-		points += config.Get().Points.ThreeOfAKind
+		total += points.ThreeOfAKind
 	case isTwoPair(hand):
-		points += config.Get().Points.TwoPair
+		total += points.TwoPair
 	case isPair(hand):
-		points += config.Get().Points.Pair
+		total += points.Pair
 	}
 
-	return points
+	return total
 }
 
-func (s *service) getUpdatedScoreBoard() []models.UpdatedScore {
-	ctx := context.Background()
-	scores, err := s.repo.GetPlayerScores(ctx)
+// getUpdatedScoreBoard scores the hands of the seated players, including
+// those the bot is playing for since they left.
+func (s *service) getUpdatedScoreBoard(ctx context.Context) ([]models.UpdatedScore, error) {
+	scores, err := s.repo.GetPlayerScoresOf(ctx, s.seatedPlayers())
 	if err != nil {
 		s.log.ErrorContext(ctx, "failed to get player score", "error", err)
-		panic(err)
+		return nil, err
 	}
 
 	updatedScores := make([]models.UpdatedScore, 0, len(scores))
@@ -615,10 +808,10 @@ func (s *service) getUpdatedScoreBoard() []models.UpdatedScore {
 		hand, err := s.repo.GetPlayerHand(ctx, score.PlayerID)
 		if err != nil {
 			s.log.ErrorContext(ctx, "failed to get player hand", "error", err, "player_id", score.PlayerID)
-			panic(err)
+			return nil, err
 		}
 
-		roundPoints := calculateRoundPoints(hand)
+		roundPoints := calculateRoundPoints(s.rules().Points, hand)
 
 		updatedScores = append(updatedScores, models.UpdatedScore{
 			PlayerID:    score.PlayerID,
@@ -629,11 +822,10 @@ func (s *service) getUpdatedScoreBoard() []models.UpdatedScore {
 		})
 	}
 
-	return updatedScores
+	return updatedScores, nil
 }
 
-func (s *service) sendOfferBackToPlayer(playerID int, card models.Card) {
-	ctx := context.Background()
+func (s *service) sendOfferBackToPlayer(ctx context.Context, playerID int, card models.Card) error {
 
 	event := models.OfferSelectedEvent{
 		Type: models.EventTypeOfferSelected,
@@ -642,100 +834,133 @@ func (s *service) sendOfferBackToPlayer(playerID int, card models.Card) {
 		},
 	}
 
-	payload, err := json.Marshal(event)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to marshal player offer", "error", err)
-		panic(err)
-	}
-
-	err = s.m.BroadcastFilter(payload, func(session *melody.Session) bool {
-		pID, ok := getAs[int](s.log, session, PlayerIDKey)
-		return ok && pID == playerID
-	})
-
-	if err != nil {
+	if err := s.publish(ctx, toPlayer(playerID, event)); err != nil {
 		s.log.ErrorContext(ctx, "failed to broadcast player offer", "error", err)
-		panic(err)
+		return err
 	}
+
+	return nil
 }
 
-func (s *service) startPlayersOffers(bid models.Card) {
-	ctx := context.Background()
+func (s *service) startPlayersOffers(ctx context.Context, bid models.Card) error {
 	currentPlayerID, err := s.repo.GetCurrentPlayerID(ctx)
 	if err != nil {
 		s.log.ErrorContext(ctx, "failed to get current player ID", "error", err)
-		panic(err)
-	}
-
-	playerIDs, err := s.repo.GetActivePlayerIDs(ctx)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to get active player IDs", "error", err)
-		panic(err)
+		return err
 	}
 
-	playerIDs = slices.DeleteFunc(playerIDs, func(id int) bool {
+	playerIDs := slices.DeleteFunc(s.seatedPlayers(), func(id int) bool {
 		return id == currentPlayerID
 	})
 
-	timeout := time.Duration(config.Get().Timeouts.PlayerChooseOfferMilliseconds) * time.Millisecond
+	hands := make(map[int][]models.Card, len(playerIDs))
+	for _, playerID := range playerIDs {
+		if hands[playerID], err = s.repo.GetPlayerHand(ctx, playerID); err != nil {
+			s.log.ErrorContext(ctx, "failed to get player hand", "error", err,
+				"player_id", playerID,
+			)
+			return err
+		}
+	}
+
+	timeout := milliseconds(s.rules().Timeouts.ChooseOffer)
+	offers := s.offers.open(playerIDs, func(offer models.PlayerOffer) error {
+		return checkInHand(hands[offer.PlayerID], offer.Card)
+	})
+	defer s.offers.close()
+	s.setPhase(ctx, models.PhaseOffers)
 
 	event := models.ChooseOfferEvent{
 		Type: models.EventTypeChooseOffer,
 		EventData: models.ChooseOffer{
 			PlayerIDs: playerIDs,
 			Timeout:   timeout.Milliseconds(),
+			Deadline:  deadlineIn(timeout),
 		},
 	}
 
-	payload, err := json.Marshal(event)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to marshal choose_offer event", "error", err)
-		panic(err)
-	}
-
-	err = s.m.BroadcastFilter(payload, func(session *melody.Session) bool {
-		pID, ok := getAs[int](s.log, session, PlayerIDKey)
-		return !ok || slices.Contains(playerIDs, pID)
-	})
-
-	if err != nil {
+	if err := s.publish(ctx, toTableAndPlayers(playerIDs, event)); err != nil {
 		s.log.ErrorContext(ctx, "failed to broadcast choose_offer event", "error", err)
-		panic(err)
+		return err
 	}
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
 	s.log.DebugContext(ctx, "choose_offer event broadcasted", "player_ids", playerIDs)
 
-	playerDidOffer := make([]int, 0, len(playerIDs))
-	playerOffersMap := make(map[int]models.Card, 3)
-	for _, playerID := range playerIDs {
-		playerHand, err := s.repo.GetPlayerHand(ctx, playerID)
-		if err != nil {
-			s.log.ErrorContext(ctx, "failed to get player hand", "error", err,
-				"player_id", playerID,
-			)
-			panic(err)
-		}
+	// The players who are not AFK have until the timeout to offer. The bot
+	// offers for the AFK ones once they are done, or after a short delay when
+	// nobody is left to wait for, unless they come back and offer first.
+	humans := s.afk.humans(playerIDs)
+	phaseTimeout := timeout + s.latencyGrace(humans...)
+	if len(humans) == 0 {
+		phaseTimeout = s.botDelay()
+	}
+	timer := s.startPhaseTimer(ctx, phaseTimeout)
+	defer timer.stop()
 
-		playerOffersMap[playerID] = playerHand[rand.IntN(len(playerHand))]
-		s.log.DebugContext(ctx, "selected player offer", "player_id", playerID, "offer", playerOffersMap[playerID])
+	playerDidOffer := make([]int, 0, len(playerIDs))
+	playerOffersMap := make(map[int]models.Card, len(playerIDs))
+	waiting := func() bool {
+		return len(humans) == 0 || slices.ContainsFunc(s.afk.humans(playerIDs), func(id int) bool {
+			return !slices.Contains(playerDidOffer, id)
+		})
 	}
 
-	for count := 0; count < len(playerIDs); count++ {
+	for timedOut := false; !timedOut && waiting(); {
 		select {
-		case playerChoice := <-offerSelectedChan:
+		case playerChoice := <-offers:
+			s.afk.acted(playerChoice.PlayerID)
 			playerOffersMap[playerChoice.PlayerID] = playerChoice.Card
 			playerDidOffer = append(playerDidOffer, playerChoice.PlayerID)
-			s.sendOfferBackToPlayer(playerChoice.PlayerID, playerChoice.Card)
-			s.sendPlayerOfferEvent(playerDidOffer)
-		case <-ctx.Done():
-			s.log.DebugContext(ctx, "timeout reached for player offers")
-			count = len(playerIDs) // Force exit the loop
-			for _, playerID := range playerIDs {
-				s.sendOfferBackToPlayer(playerID, playerOffersMap[playerID])
+			s.turn.addOffer(playerChoice)
+			s.turn.setPlayersOffered(playerDidOffer)
+			if err := s.sendOfferBackToPlayer(ctx, playerChoice.PlayerID, playerChoice.Card); err != nil {
+				return err
+			}
+			if err := s.sendPlayerOfferEvent(ctx, playerDidOffer); err != nil {
+				return err
 			}
+		case <-timer.done:
+			s.log.DebugContext(ctx, "timeout reached for player offers")
+			timedOut = true
 		}
 	}
+	timer.stop()
+	s.offers.close()
+
+	autoOffered := make([]int, 0, len(playerIDs))
+	for _, playerID := range playerIDs {
+		if slices.Contains(playerDidOffer, playerID) {
+			continue
+		}
+
+		if s.afk.isAFK(playerID) {
+			playerOffersMap[playerID] = s.bot.offer(hands[playerID])
+			s.sendAutoAction(ctx, offerAction(playerID, models.AutoActionReasonAFK, playerOffersMap[playerID]))
+		} else {
+			playerOffersMap[playerID] = s.autoPick.offer(hands[playerID])
+			s.sendAutoAction(ctx, offerAction(playerID, models.AutoActionReasonTimeout, playerOffersMap[playerID]))
+			s.playerTimedOut(ctx, playerID)
+		}
+		s.log.DebugContext(ctx, "selected player offer", "player_id", playerID, "offer", playerOffersMap[playerID])
+		s.turn.addOffer(models.PlayerOffer{PlayerID: playerID, Card: playerOffersMap[playerID]})
+
+		if err := s.sendOfferBackToPlayer(ctx, playerID, playerOffersMap[playerID]); err != nil {
+			return err
+		}
+		autoOffered = append(autoOffered, playerID)
+	}
+
+	if len(autoOffered) > 0 {
+		playerDidOffer = append(playerDidOffer, autoOffered...)
+		s.turn.setPlayersOffered(playerDidOffer)
+		if err := s.sendPlayerOfferEvent(ctx, playerDidOffer); err != nil {
+			return err
+		}
+	}
+	s.setPhase(ctx, models.PhaseShowOffers)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	playerOffers := make([]models.PlayerOffer, 0, len(playerOffersMap))
 	for playerID, card := range playerOffersMap {
@@ -744,58 +969,93 @@ func (s *service) startPlayersOffers(bid models.Card) {
 			Card:     card,
 		})
 	}
-	s.sendAllPlayerOffersEvent(playerOffers, currentPlayerID)
+	s.turn.setOffers(playerOffers)
+
+	if err := s.sendAllPlayerOffersEvent(ctx, playerOffers, currentPlayerID); err != nil {
+		return err
+	}
 
-	s.startCurrentPlayerChoosesOffer(bid, playerOffers, currentPlayerID)
+	return s.startCurrentPlayerChoosesOffer(ctx, bid, playerOffers, currentPlayerID)
 }
 
-func (s *service) startCurrentPlayerChoosesOffer(bid models.Card, playerOffers []models.PlayerOffer, currentPlayerID int) {
-	ctx := context.Background()
+func (s *service) startCurrentPlayerChoosesOffer(ctx context.Context, bid models.Card, playerOffers []models.PlayerOffer, currentPlayerID int) error {
 
 	s.log.DebugContext(ctx, "starting current player chooses offer", "player_id", currentPlayerID)
 
-	timeout := time.Duration(config.Get().Timeouts.PlayerChooseOfferMilliseconds) * time.Millisecond
+	timeout := milliseconds(s.rules().Timeouts.ChooseOffer)
+	chosenOffers := s.chosenOffers.open([]int{currentPlayerID}, func(playerID int) error {
+		if !slices.ContainsFunc(playerOffers, func(offer models.PlayerOffer) bool { return offer.PlayerID == playerID }) {
+			return newClientError(models.ErrorCodeInvalidPayload, fmt.Sprintf("player %d made no offer", playerID))
+		}
+		return nil
+	})
+	defer s.chosenOffers.close()
+	s.setPhase(ctx, models.PhaseChooseOffer)
 
 	playerChooseOfferEvent := models.SelectOfferChoicesEvent{
 		Type: models.EventTypeSelectOfferChoices,
 		EventData: models.SelectOfferChoices{
-			Offers:  playerOffers,
-			Timeout: timeout.Milliseconds(),
+			Offers:   offerFaces(playerOffers),
+			Timeout:  timeout.Milliseconds(),
+			Deadline: deadlineIn(timeout),
 		},
 	}
 
-	payload, err := json.Marshal(playerChooseOfferEvent)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to marshal select_offer_choices event", "error", err)
-		panic(err)
-	}
-
-	if err := s.broadcastToPlayerAndHub(payload, currentPlayerID); err != nil {
+	if err := s.publish(ctx, toCurrentPlayer(playerChooseOfferEvent)); err != nil {
 		s.log.ErrorContext(ctx, "failed to broadcast select_offer_choices event", "error", err)
-		panic(err)
+		return err
 	}
 
 	s.log.DebugContext(ctx, "select_offer_choices event broadcasted", "offers", playerOffers, "current_player", currentPlayerID)
 
-	selectedOfferIndex := rand.IntN(len(playerOffers))
+	hand, err := s.repo.GetPlayerHand(ctx, currentPlayerID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to get current player hand", "error", err)
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
+	var offererID int
+	afk := s.afk.isAFK(currentPlayerID)
+	phaseTimeout := timeout + s.latencyGrace(currentPlayerID)
+	if afk {
+		phaseTimeout = s.botDelay()
+	}
+	timer := s.startPhaseTimer(ctx, phaseTimeout)
 
 	select {
-	case playerID := <-currentPlayerSelectedOfferChan:
-		selectedOfferIndex = slices.IndexFunc(playerOffers, func(offer models.PlayerOffer) bool {
-			return offer.PlayerID == playerID
-		})
-	case <-ctx.Done():
+	case offererID = <-chosenOffers:
+		s.afk.acted(currentPlayerID)
+	case <-timer.done:
+		if afk {
+			offererID = s.bot.chooseOffer(hand, bid, offerFaces(playerOffers))
+			s.sendAutoAction(ctx, chooseOfferAction(currentPlayerID, models.AutoActionReasonAFK, offererID))
+			break
+		}
+		offererID = s.autoPick.chooseOffer(hand, bid, offerFaces(playerOffers))
+		s.sendAutoAction(ctx, chooseOfferAction(currentPlayerID, models.AutoActionReasonTimeout, offererID))
+		s.playerTimedOut(ctx, currentPlayerID)
+	}
+	timer.stop()
+	s.chosenOffers.close()
+	s.setPhase(ctx, models.PhaseShowChosenOffer)
+
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	offererID := playerOffers[selectedOfferIndex].PlayerID
-	err = s.repo.SwapCardHolders(ctx, bid, playerOffers[selectedOfferIndex].Card, currentPlayerID, offererID)
+	selected := slices.IndexFunc(playerOffers, func(offer models.PlayerOffer) bool {
+		return offer.PlayerID == offererID
+	})
+	err = s.repo.SwapCardHolders(ctx, bid, playerOffers[selected].Card, currentPlayerID, offererID)
 	if err != nil {
 		s.log.Error("Failed to swap card holders", "error", err)
-		panic(err)
+		return err
+	}
+
+	if err := s.checkpoint(ctx, models.ResumeNextTurn); err != nil {
+		return err
 	}
+
 	errGroup := &errgroup.Group{}
 	//send update hand to current player
 	errGroup.Go(func() error {
@@ -812,16 +1072,7 @@ func (s *service) startCurrentPlayerChoosesOffer(bid models.Card, playerOffers [
 			},
 		}
 
-		payload, err := json.Marshal(updateCardsEvent)
-		if err != nil {
-			s.log.ErrorContext(ctx, "failed to marshal update_cards event", "error", err)
-			return err
-		}
-
-		err = s.m.BroadcastFilter(payload, func(session *melody.Session) bool {
-			playerID, ok := getAs[int](s.log, session, PlayerIDKey)
-			return ok && playerID == currentPlayerID
-		})
+		err = s.publish(ctx, toPlayer(currentPlayerID, updateCardsEvent))
 		if err != nil {
 			s.log.ErrorContext(ctx, "failed to broadcast update_cards event", "error", err)
 			return err
@@ -845,16 +1096,7 @@ func (s *service) startCurrentPlayerChoosesOffer(bid models.Card, playerOffers [
 			},
 		}
 
-		payload, err := json.Marshal(updateCardsEvent)
-		if err != nil {
-			s.log.ErrorContext(ctx, "failed to marshal update_cards event", "error", err)
-			return err
-		}
-
-		err = s.m.BroadcastFilter(payload, func(session *melody.Session) bool {
-			playerID, ok := getAs[int](s.log, session, PlayerIDKey)
-			return ok && playerID == offererID
-		})
+		err = s.publish(ctx, toPlayer(offererID, updateCardsEvent))
 		if err != nil {
 			s.log.ErrorContext(ctx, "failed to broadcast update_cards event", "error", err)
 			return err
@@ -865,54 +1107,44 @@ func (s *service) startCurrentPlayerChoosesOffer(bid models.Card, playerOffers [
 
 	//notify hub
 	errGroup.Go(func() error {
-		timeout := time.Duration(config.Get().Timeouts.ShowSelectedOffer) * time.Millisecond
+		timeout := milliseconds(s.rules().Timeouts.ShowSelectedOffer)
 
 		event := models.SelectOfferChosenEvent{
 			Type: models.EventTypeSelectOfferChosen,
 			EventData: models.SelectOfferChosen{
 				Timeout:  timeout.Milliseconds(),
+				Deadline: deadlineIn(timeout),
 				PlayerID: offererID,
 			},
 		}
-		payload, err := json.Marshal(event)
-		if err != nil {
-			s.log.ErrorContext(ctx, "failed to marshal select_offer_chosen event", "error", err)
-			return err
-		}
-
-		err = s.m.BroadcastFilter(payload, func(session *melody.Session) bool {
-			_, ok := getAs[int](s.log, session, PlayerIDKey)
-			return !ok
-		})
+		err := s.publish(ctx, toTable(event))
 		if err != nil {
 			s.log.ErrorContext(ctx, "failed to broadcast select_offer_chosen event", "error", err)
 			return err
 		}
 
-		time.Sleep(timeout)
-		return nil
+		return s.wait(ctx, timeout)
 	})
 
 	if err := errGroup.Wait(); err != nil {
-		panic(err)
+		return err
 	}
 
-	s.prepareForNextTurn()
+	return s.prepareForNextTurn(ctx)
 }
 
-func (s *service) prepareForNextTurn() {
-	ctx := context.Background()
+func (s *service) prepareForNextTurn(ctx context.Context) error {
+	s.setPhase(ctx, models.PhaseNextTurn)
 
 	currentPlayerID, err := s.repo.GetCurrentPlayerID(ctx)
 	if err != nil {
-		s.log.ErrorContext(context.Background(), "failed to get current player ID", "error", err)
-		panic(err)
+		s.log.ErrorContext(ctx, "failed to get current player ID", "error", err)
+		return err
 	}
 
-	playerIDs, err := s.repo.GetActivePlayerIDs(ctx)
-	if err != nil {
-		s.log.ErrorContext(context.Background(), "failed to get active player IDs", "error", err)
-		panic(err)
+	playerIDs := s.seatedPlayers()
+	if len(playerIDs) == 0 {
+		return ErrInvalidPlayerCount
 	}
 
 	currentPlayerIndex := slices.Index(playerIDs, currentPlayerID)
@@ -921,72 +1153,79 @@ func (s *service) prepareForNextTurn() {
 	currentPlayerID = playerIDs[currentPlayerIndex]
 	if err := s.repo.SetCurrentPlayerID(ctx, currentPlayerID); err != nil {
 		s.log.ErrorContext(ctx, "failed to set current player ID", "error", err)
-		panic(err)
+		return err
+	}
+
+	if err := s.checkpoint(ctx, models.ResumeTurn); err != nil {
+		return err
 	}
 
-	timeout := time.Duration(config.Get().Timeouts.PrepareForNextTurnMilliseconds) * time.Millisecond
+	timeout := milliseconds(s.rules().Timeouts.PrepareForNextTurn)
 	event := models.PrepareForNextTurnEvent{
 		Type: models.EventTypePrepareForNextTurn,
 		EventData: models.PrepareForNextTurn{
 			Timeout:    timeout.Milliseconds(),
+			Deadline:   deadlineIn(timeout),
 			NextBidder: currentPlayerID,
 		},
 	}
 
-	payload, err := json.Marshal(event)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to marshal prepare_for_next_turn event", "error", err)
-		panic(err)
-	}
-
-	if err := s.broadcastToPlayerAndHub(payload, currentPlayerID); err != nil {
+	if err := s.publish(ctx, toCurrentPlayer(event)); err != nil {
 		s.log.ErrorContext(ctx, "failed to broadcast prepare_for_next_turn event", "error", err)
-		panic(err)
+		return err
 	}
 
-	time.Sleep(timeout)
-
-	s.startTurn()
+	return s.wait(ctx, timeout)
 }
 
-func (s *service) sendAllPlayerOffersEvent(playerOffers []models.PlayerOffer, currentPlayerID int) {
+func (s *service) sendAllPlayerOffersEvent(ctx context.Context, playerOffers []models.PlayerOffer, currentPlayerID int) error {
 	playerIDs := make([]int, 0, len(playerOffers))
 	for _, offer := range playerOffers {
 		playerIDs = append(playerIDs, offer.PlayerID)
 	}
-	s.sendPlayerOfferEvent(playerIDs)
+	if err := s.sendPlayerOfferEvent(ctx, playerIDs); err != nil {
+		return err
+	}
+
+	// Give the table time to show every offer before the current player
+	// picks one.
+	if err := s.wait(ctx, milliseconds(s.rules().Timeouts.ShowOffer)); err != nil {
+		return err
+	}
 
-	ctx := context.Background()
 	s.log.DebugContext(ctx, "sending all player offers event", "player_offers", playerOffers)
 
-	timeout := time.Duration(config.Get().Timeouts.OffersFinishedMilliseconds) * time.Millisecond
+	timeout := milliseconds(s.rules().Timeouts.OffersFinished)
 
 	event := models.OffersFinishedEvent{
-		Type: models.EventTypeOfferSelected,
+		Type: models.EventTypeOffersFinished,
 		EventData: models.OffersFinished{
-			Timeout: timeout.Milliseconds(),
-			Offers:  playerOffers,
+			Timeout:  timeout.Milliseconds(),
+			Deadline: deadlineIn(timeout),
+			Offers:   offerFaces(playerOffers),
 		},
 	}
 
-	payload, err := json.Marshal(event)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to marshal offers_finished event", "error", err)
-		panic(err)
+	if err := s.publish(ctx, toCurrentPlayer(event)); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast offers_finished event", "error", err)
+		return err
 	}
 
-	s.broadcastToPlayerAndHub(payload, currentPlayerID)
-
-	time.Sleep(time.Duration(config.Get().Timeouts.TimeBetweenActionsMilliseconds) * time.Millisecond)
+	if err := s.wait(ctx, milliseconds(s.rules().Timeouts.BetweenActions)); err != nil {
+		return err
+	}
 
-	s.broadcastToPlayerAndHub(payload, currentPlayerID)
+	event.EventData.Deadline = deadlineIn(timeout)
+	if err := s.publish(ctx, toCurrentPlayer(event)); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast offers_finished event", "error", err)
+		return err
+	}
 	s.log.DebugContext(ctx, "offers_finished event sent", "player_offers", playerOffers)
-	time.Sleep(timeout)
 
+	return s.wait(ctx, timeout)
 }
 
-func (s *service) sendPlayerOfferEvent(playerIDs []int) {
-	ctx := context.Background()
+func (s *service) sendPlayerOfferEvent(ctx context.Context, playerIDs []int) error {
 
 	s.log.DebugContext(ctx, "sending player offer event", "player_ids", playerIDs)
 
@@ -997,70 +1236,66 @@ func (s *service) sendPlayerOfferEvent(playerIDs []int) {
 		},
 	}
 
-	payload, err := json.Marshal(event)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to marshal player_offer event", "error", err)
-		panic(err)
-	}
-
-	err = s.m.BroadcastFilter(payload, func(session *melody.Session) bool {
-		_, ok := getAs[int](s.log, session, PlayerIDKey)
-		return !ok
-	})
+	err := s.publish(ctx, toTable(event))
 	if err != nil {
 		s.log.ErrorContext(ctx, "failed to broadcast player_offer event", "error", err)
-		panic(err)
+		return err
 	}
 
 	s.log.DebugContext(ctx, "player_offer event sent", "player_ids", playerIDs)
+
+	return nil
 }
 
-func (s *service) sendPlayerBidWasSelectedEvent(choice models.Card, playerID int) {
-	ctx := context.Background()
+func (s *service) sendPlayerBidWasSelectedEvent(ctx context.Context, choice models.Card, playerID int) error {
 
-	timeout := time.Duration(config.Get().Timeouts.ShowBidMilliseconds) * time.Millisecond
+	timeout := milliseconds(s.rules().Timeouts.ShowBid)
 	showBackCardEvent := models.ShowBackOfCardBidEvent{
 		Type: models.EventTypeShowBackOfCardBid,
 		EventData: models.ShowBackOfCardBid{
 			Timetout: timeout.Milliseconds(),
+			Deadline: deadlineIn(timeout),
 		},
 	}
 
-	payload, err := json.Marshal(showBackCardEvent)
-	if err != nil {
-		s.log.ErrorContext(ctx, "Failed to marshal show back of card event", "error", err)
-		panic(err)
+	if err := s.publish(ctx, toTable(showBackCardEvent)); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast show back of card event", "error", err)
+		return err
 	}
 
-	err = s.m.BroadcastFilter(payload, func(session *melody.Session) bool {
-		_, ok := getAs[int](s.log, session, PlayerIDKey)
-		return !ok
-	})
-
-	time.Sleep(timeout)
+	if err := s.wait(ctx, timeout); err != nil {
+		return err
+	}
 	s.log.DebugContext(ctx, "sending how choice event", "player_id", playerID, "card", choice)
 
-	timeout = time.Duration(config.Get().Timeouts.ShowBidMilliseconds) * time.Millisecond
+	timeout = milliseconds(s.rules().Timeouts.ShowBid)
 
 	event := models.ShowBidSelectedEvent{
 		Type: models.EventTypeBidSelected,
 		EventData: models.ShowBidSelected{
-			Card:    choice,
-			Timeout: timeout.Milliseconds(),
+			Card:     choice,
+			Timeout:  timeout.Milliseconds(),
+			Deadline: deadlineIn(timeout),
 		},
 	}
 
-	payload, err = json.Marshal(event)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to marshal bid_selected event", "error", err)
-		return
+	if err := s.publish(ctx, toCurrentPlayer(event)); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast bid_selected event", "error", err)
+		return err
 	}
 
-	s.broadcastToPlayerAndHub(payload, playerID)
-
 	s.log.DebugContext(ctx, "bid_selected event sent", "player_id", playerID, "card", choice)
 
-	time.Sleep(timeout)
+	return s.wait(ctx, timeout)
+}
+
+// checkInHand turns down a card the player doesn't hold.
+func checkInHand(hand []models.Card, card models.Card) error {
+	if !slices.Contains(hand, card) {
+		return newClientError(models.ErrorCodeInvalidPayload, "card not in your hand")
+	}
+
+	return nil
 }
 
 func canFinishRound(hand []models.Card) bool {
@@ -1073,70 +1308,41 @@ func canFinishRound(hand []models.Card) bool {
 
 }
 
-func (s *service) sendPlayerBidOfferEvent(ctx context.Context, playerID int, timeout time.Duration) {
-
-	hand, err := s.repo.GetPlayerHand(ctx, playerID)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to get player hand",
-			"player_id", playerID,
-			"error", err,
-		)
-		panic(err)
-	}
-
+func (s *service) sendPlayerBidOfferEvent(ctx context.Context, playerID int, hand []models.Card, timeout time.Duration) error {
 	s.log.DebugContext(ctx, "sending player bid offer event", "player_id", playerID)
 	event := models.ChooseBidEvent{
 		Type: models.EventTypeChooseBid,
 		EventData: models.ChooseBid{
 			PlayerID:       playerID,
 			Timeout:        timeout.Milliseconds(),
+			Deadline:       deadlineIn(timeout),
 			CanFinishRound: canFinishRound(hand),
 		},
 	}
 
-	payload, err := json.Marshal(event)
-	if err != nil {
-		s.log.ErrorContext(ctx, "failed to marshal player bid offer event", "error", err)
-		return
+	if err := s.publish(ctx, toCurrentPlayer(event)); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast choose_bid event", "error", err)
+		return err
 	}
 
-	s.broadcastToPlayerAndHub(payload, playerID)
-}
-
-func (s *service) broadcastToPlayerAndHub(payload []byte, playerID int) error {
-	return s.m.BroadcastFilter(payload, func(session *melody.Session) bool {
-		pID, ok := getAs[int](s.log, session, PlayerIDKey)
-		return ok && pID == playerID
-	})
+	return nil
 }
 
-func shuffleAndGiveCardsToPlayers(playerIDs []int) map[int][]models.Card {
+func shuffleAndGiveCardsToPlayers(playerIDs []int, ruleset models.Ruleset) map[int][]models.Card {
 	cardsForthisRound := slices.Clone(models.AvailableRealCards)
+	handSize := ruleset.HandSize()
 
-	fakeCards := slices.Clone(models.AvailableFakeCards)
-	rand.Shuffle(len(fakeCards), func(i, j int) {
-		fakeCards[i], fakeCards[j] = fakeCards[j], fakeCards[i]
-	})
-
-	fakeCount := 0
-	selectedFakeCards := make([]models.Card, 0, 4)
-	for fakeCount < 4 && len(fakeCards) > 0 {
-		if !slices.Contains(selectedFakeCards, fakeCards[0]) {
-			selectedFakeCards = append(selectedFakeCards, fakeCards[0])
-			fakeCount++
-		}
-		fakeCards = fakeCards[1:]
+	if ruleset == models.RulesetClassic {
+		cardsForthisRound = append(cardsForthisRound, pickFakeCards()...)
 	}
 
-	cardsForthisRound = append(cardsForthisRound, selectedFakeCards...)
-
 	rand.Shuffle(len(cardsForthisRound), func(i, j int) {
 		cardsForthisRound[i], cardsForthisRound[j] = cardsForthisRound[j], cardsForthisRound[i]
 	})
 
 	playerHands := map[int][]models.Card{}
 	for _, playerID := range playerIDs {
-		for range 5 {
+		for range handSize {
 			playerHands[playerID] = append(playerHands[playerID], cardsForthisRound[0])
 			cardsForthisRound = cardsForthisRound[1:]
 		}
@@ -1144,3 +1350,22 @@ func shuffleAndGiveCardsToPlayers(playerIDs []int) map[int][]models.Card {
 
 	return playerHands
 }
+
+func pickFakeCards() []models.Card {
+	fakeCards := slices.Clone(models.AvailableFakeCards)
+	rand.Shuffle(len(fakeCards), func(i, j int) {
+		fakeCards[i], fakeCards[j] = fakeCards[j], fakeCards[i]
+	})
+
+	fakeCount := 0
+	selectedFakeCards := make([]models.Card, 0, models.ClassicFakeCards)
+	for fakeCount < models.ClassicFakeCards && len(fakeCards) > 0 {
+		if !slices.Contains(selectedFakeCards, fakeCards[0]) {
+			selectedFakeCards = append(selectedFakeCards, fakeCards[0])
+			fakeCount++
+		}
+		fakeCards = fakeCards[1:]
+	}
+
+	return selectedFakeCards
+}
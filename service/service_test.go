@@ -0,0 +1,798 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/Jubris-Knifes/wgj25-back/repository"
+	"github.com/Jubris-Knifes/wgj25-back/transport"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/gorilla/websocket"
+	"github.com/olahol/melody"
+	_ "modernc.org/sqlite"
+)
+
+// testTable is a service on an in-memory database, reachable over a real
+// websocket server. Game events go to recorder instead of the sessions.
+type testTable struct {
+	svc      *service
+	repo     *repository.Repository
+	recorder *transport.Recorder
+	url      string
+}
+
+func newTestTable(t *testing.T) *testTable {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://../migrations", "sqlite", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := repository.New(logger, db)
+	recorder := transport.NewRecorder()
+	svc, err := New(logger, repo, transport.NewRegistry(), recorder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.settings.TableSize = 2
+	svc.settings.Timeouts = models.TimeoutSettings{
+		ChooseBid:          10,
+		ShowBid:            1,
+		ChooseOffer:        10,
+		ShowOffer:          1,
+		BetweenActions:     1,
+		OffersFinished:     1,
+		ShowSelectedOffer:  1,
+		PrepareForNextTurn: 1,
+		EndOfRound:         1,
+		UpdateScore:        1,
+		SumScore:           1,
+	}
+	t.Cleanup(func() { svc.stopGame() })
+
+	ws := melody.New()
+	ws.HandleConnect(svc.NewConnection)
+	ws.HandleDisconnect(svc.ClosedConnection)
+	ws.HandleMessage(svc.HandleMessage)
+	ws.HandleSentMessage(func(s *melody.Session, _ []byte) { transport.MessageSent(s) })
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws.HandleRequest(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return &testTable{
+		svc:      svc,
+		repo:     repo,
+		recorder: recorder,
+		url:      "ws" + strings.TrimPrefix(server.URL, "http"),
+	}
+}
+
+type testClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+type testEnvelope struct {
+	Type      models.EventType `json:"type"`
+	EventData json.RawMessage  `json:"event_data"`
+}
+
+// connect opens a session and completes the handshake with role.
+func (tt *testTable) connect(t *testing.T, role models.Role) *testClient {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(tt.url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	c := &testClient{t: t, conn: conn}
+	c.send(models.EventTypeHello, models.Hello{ProtocolVersion: models.ProtocolVersion, Role: role})
+
+	var response models.HelloResponse
+	c.read(models.EventTypeHelloResponse, &response)
+	if !response.Accepted {
+		t.Fatalf("handshake rejected: %s", response.Reason)
+	}
+
+	return c
+}
+
+func (c *testClient) send(eventType models.EventType, data any) {
+	c.t.Helper()
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	if err := c.conn.WriteJSON(models.EnvelopeIn{Type: eventType, EventData: payload}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// read skips events until one of eventType or an error comes in, and decodes
+// it into v. It returns the type of the event it stopped at.
+func (c *testClient) read(eventType models.EventType, v any) models.EventType {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var envelope testEnvelope
+		if err := c.conn.ReadJSON(&envelope); err != nil {
+			c.t.Fatalf("waiting for %s: %v", eventType, err)
+		}
+
+		if envelope.Type != eventType && envelope.Type != models.EventTypeError {
+			continue
+		}
+
+		if err := json.Unmarshal(envelope.EventData, v); err != nil {
+			c.t.Fatal(err)
+		}
+
+		return envelope.Type
+	}
+}
+
+// setName joins as name and returns the assigned player id.
+func (c *testClient) setName(name string) int {
+	c.t.Helper()
+
+	c.send(models.EventTypeSetName, models.SetName{Name: name})
+
+	var response models.SetNameResponse
+	if eventType := c.read(models.EventTypeSetNameResponse, &response); eventType != models.EventTypeSetNameResponse {
+		c.t.Fatalf("set_name %q failed", name)
+	}
+
+	return response.AssignedPlayerID
+}
+
+// setNameError joins as name expecting to be turned down, and returns the
+// error code.
+func (c *testClient) setNameError(name string) models.ErrorCode {
+	c.t.Helper()
+
+	return c.sendError(models.EventTypeSetName, models.SetName{Name: name})
+}
+
+// sendError sends an event expecting it to be turned down, and returns the
+// error code.
+func (c *testClient) sendError(eventType models.EventType, data any) models.ErrorCode {
+	c.t.Helper()
+
+	c.send(eventType, data)
+
+	var details models.ErrorDetails
+	if got := c.read(models.EventTypeError, &details); got != models.EventTypeError {
+		c.t.Fatalf("%s was accepted", eventType)
+	}
+
+	return details.Code
+}
+
+func eventsOf[T any](events []any) []T {
+	var matching []T
+	for _, event := range events {
+		if e, ok := event.(T); ok {
+			matching = append(matching, e)
+		}
+	}
+
+	return matching
+}
+
+// waitFor polls until done reports true.
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tt := newTestTable(t)
+
+	first := tt.connect(t, models.RolePlayer)
+	ana := first.setName("ana")
+	if code := first.setNameError("anna"); code != models.ErrorCodeNotAllowed {
+		t.Errorf("joining twice from one session got %q, want %q", code, models.ErrorCodeNotAllowed)
+	}
+
+	late := tt.connect(t, models.RolePlayer)
+	if code := late.setNameError("ana"); code != models.ErrorCodeNameTaken {
+		t.Errorf("joining with a taken name got %q, want %q", code, models.ErrorCodeNameTaken)
+	}
+
+	bo := late.setName("bo")
+
+	waitFor(t, "both players on the roster", func() bool {
+		rosters := eventsOf[models.LobbyRosterEvent](tt.recorder.EventsForHubs())
+		return len(rosters) > 0 && len(rosters[len(rosters)-1].EventData.Players) == 2
+	})
+	if got := len(eventsOf[models.LobbyRosterEvent](tt.recorder.EventsForPlayer(ana))); got == 0 {
+		t.Errorf("player %d got no roster", ana)
+	}
+	if got := len(eventsOf[models.LobbyRosterEvent](tt.recorder.EventsForPlayer(bo))); got == 0 {
+		t.Errorf("player %d got no roster", bo)
+	}
+
+	full := tt.connect(t, models.RolePlayer)
+	if code := full.setNameError("cy"); code != models.ErrorCodePlayerCountTooHigh {
+		t.Errorf("joining a full table got %q, want %q", code, models.ErrorCodePlayerCountTooHigh)
+	}
+}
+
+func TestRoomCodeOutlivesHub(t *testing.T) {
+	tt := newTestTable(t)
+
+	hub := tt.connect(t, models.RoleHub)
+	hub.send(models.EventTypeCreateRoom, models.CreateRoom{})
+	var room models.Room
+	hub.read(models.EventTypeRoom, &room)
+
+	hub.conn.Close()
+	waitFor(t, "the hub to disconnect", func() bool {
+		return len(tt.svc.sessions.All()) == 0
+	})
+
+	player := tt.connect(t, models.RolePlayer)
+	player.send(models.EventTypeSetName, models.SetName{Name: "ana", RoomCode: " " + strings.ToLower(room.Code) + " "})
+	var response models.SetNameResponse
+	if eventType := player.read(models.EventTypeSetNameResponse, &response); eventType != models.EventTypeSetNameResponse {
+		t.Errorf("joining with the code of a hub that left was turned down")
+	}
+}
+
+func TestKickPlayerWithoutSession(t *testing.T) {
+	tt := newTestTable(t)
+	ctx := context.Background()
+
+	ghost, err := tt.repo.NewPlayer(ctx, "ghost", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt.svc.lobby.join(ghost)
+	if err := tt.repo.SetResumeToken(ctx, ghost, "ghost-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	ana := tt.connect(t, models.RolePlayer).setName("ana")
+
+	if err := tt.svc.KickPlayer(ctx, ghost); err != nil {
+		t.Fatalf("kicking a player with no session: %v", err)
+	}
+
+	active, err := tt.repo.GetActivePlayerIDs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0] != ana {
+		t.Errorf("active players are %v, want [%d]", active, ana)
+	}
+	if host := tt.svc.lobby.host(); host != ana {
+		t.Errorf("host is %d, want %d", host, ana)
+	}
+
+	if err := tt.svc.KickPlayer(ctx, ghost); !errors.Is(err, ErrPlayerNotAtTable) {
+		t.Errorf("kicking a player twice got %v, want %v", err, ErrPlayerNotAtTable)
+	}
+
+	if _, err := tt.repo.ResumePlayer(ctx, "ghost-token"); !errors.Is(err, repository.ErrUnknownResumeToken) {
+		t.Errorf("resuming a kicked player got %v, want %v", err, repository.ErrUnknownResumeToken)
+	}
+}
+
+func TestEndRestoredGame(t *testing.T) {
+	tt := newTestTable(t)
+	ctx := context.Background()
+
+	state := models.GameState{
+		Resume:    models.ResumeTurn,
+		Phase:     models.PhaseBid,
+		PlayerIDs: []int{1, 2},
+		Settings:  tt.svc.rules(),
+	}
+	if err := tt.repo.SaveGameState(ctx, state); err != nil {
+		t.Fatal(err)
+	}
+	if err := tt.svc.Restore(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tt.svc.EndGame(ctx); err != nil {
+		t.Fatalf("ending a restored game waiting for its players: %v", err)
+	}
+
+	if _, ok, err := tt.repo.GetGameState(ctx); err != nil || ok {
+		t.Errorf("game state still saved after ending the game, err %v", err)
+	}
+	if phase := tt.svc.currentPhase(); phase != models.PhaseLobby {
+		t.Errorf("table is in %s after ending the game, want %s", phase, models.PhaseLobby)
+	}
+}
+
+func TestRestoreTimeLeft(t *testing.T) {
+	tt := newTestTable(t)
+	ctx := context.Background()
+
+	state := models.GameState{
+		Resume:    models.ResumeTurn,
+		Phase:     models.PhaseBid,
+		TimeLeft:  300,
+		PlayerIDs: []int{1, 2},
+		Settings:  tt.svc.rules(),
+	}
+	if err := tt.repo.SaveGameState(ctx, state); err != nil {
+		t.Fatal(err)
+	}
+	if err := tt.svc.Restore(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tt.svc.gameMu.Lock()
+	tt.svc.phase = models.PhaseBid
+	tt.svc.gameMu.Unlock()
+
+	if d := tt.svc.restoredTimeLeft(time.Minute); d != 300*time.Millisecond {
+		t.Errorf("restored bid timer runs for %s, want 300ms", d)
+	}
+	if d := tt.svc.restoredTimeLeft(time.Minute); d != time.Minute {
+		t.Errorf("next bid timer runs for %s, want 1m", d)
+	}
+}
+
+func TestJoinOutsideLobby(t *testing.T) {
+	tt := newTestTable(t)
+	tt.svc.settings.TableSize = 3
+
+	tt.connect(t, models.RolePlayer).setName("ana")
+	tt.connect(t, models.RolePlayer).setName("bo")
+
+	if !tt.svc.startGame() {
+		t.Fatal("game did not start")
+	}
+	waitFor(t, "the game to leave the lobby", func() bool {
+		return tt.svc.currentPhase() != models.PhaseLobby
+	})
+
+	late := tt.connect(t, models.RolePlayer)
+	if code := late.setNameError("cy"); code != models.ErrorCodeWrongPhase {
+		t.Errorf("joining mid-game got %q, want %q", code, models.ErrorCodeWrongPhase)
+	}
+}
+
+func TestForceStartRoundKeepsGame(t *testing.T) {
+	tt := newTestTable(t)
+	ctx := context.Background()
+
+	tt.connect(t, models.RolePlayer).setName("ana")
+	tt.connect(t, models.RolePlayer).setName("bo")
+
+	if !tt.svc.startGame() {
+		t.Fatal("game did not start")
+	}
+	waitFor(t, "the players to be seated", func() bool {
+		return len(tt.svc.seatedPlayers()) == 2
+	})
+
+	tt.svc.gameMu.Lock()
+	tt.svc.roundsPlayed = 1
+	tt.svc.gameMu.Unlock()
+	seats := tt.svc.seatedPlayers()
+
+	if err := tt.svc.ForceStartRound(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !tt.svc.isGameRunning() {
+		t.Fatal("game not running after restarting the round")
+	}
+	tt.svc.gameMu.Lock()
+	roundsPlayed := tt.svc.roundsPlayed
+	tt.svc.gameMu.Unlock()
+	if roundsPlayed != 1 {
+		t.Errorf("rounds played is %d after restarting the round, want 1", roundsPlayed)
+	}
+	if got := tt.svc.seatedPlayers(); !slices.Equal(got, seats) {
+		t.Errorf("seats are %v after restarting the round, want %v", got, seats)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tt := newTestTable(t)
+
+	playerIDs := []int{
+		tt.connect(t, models.RolePlayer).setName("ana"),
+		tt.connect(t, models.RolePlayer).setName("bo"),
+	}
+
+	if !tt.svc.startGame() {
+		t.Fatal("game did not start")
+	}
+
+	waitFor(t, "a player to bid", func() bool {
+		for _, delivery := range tt.recorder.Deliveries() {
+			if _, ok := delivery.Event.(models.ChooseBidEvent); ok {
+				return true
+			}
+		}
+		return false
+	})
+	tt.svc.stopGame()
+
+	seen := map[models.Card]bool{}
+	for _, playerID := range playerIDs {
+		events := tt.recorder.EventsForPlayer(playerID)
+
+		if got := len(eventsOf[models.DealingCardsEvent](events)); got != 1 {
+			t.Errorf("player %d got %d dealing_cards, want 1", playerID, got)
+		}
+
+		dealt := eventsOf[models.CardsDealtEvent](events)
+		if len(dealt) != 1 {
+			t.Fatalf("player %d got %d cards_dealt, want 1", playerID, len(dealt))
+		}
+
+		hand, err := tt.repo.GetPlayerHand(context.Background(), playerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dealt[0].EventData.Cards) != 5 || len(hand) != 5 {
+			t.Errorf("player %d was dealt %d cards and holds %d, want 5", playerID, len(dealt[0].EventData.Cards), len(hand))
+		}
+
+		for _, card := range dealt[0].EventData.Cards {
+			if seen[card] {
+				t.Errorf("card %v dealt twice", card)
+			}
+			seen[card] = true
+		}
+	}
+
+	for name, events := range map[string][]any{
+		"hubs":       tt.recorder.EventsForHubs(),
+		"spectators": tt.recorder.EventsForSpectators(),
+	} {
+		if got := len(eventsOf[models.DealingCardsEvent](events)); got != 1 {
+			t.Errorf("%s got %d dealing_cards, want 1", name, got)
+		}
+		if got := len(eventsOf[models.CardsDealtEvent](events)); got != 0 {
+			t.Errorf("%s got %d cards_dealt, want none", name, got)
+		}
+		if got := len(eventsOf[models.ChooseBidEvent](events)); got != 0 {
+			t.Errorf("%s got %d choose_bid, want none", name, got)
+		}
+	}
+
+	bidders := 0
+	for _, playerID := range playerIDs {
+		if len(eventsOf[models.ChooseBidEvent](tt.recorder.EventsForPlayer(playerID))) > 0 {
+			bidders++
+		}
+	}
+	if bidders != 1 {
+		t.Errorf("%d players were asked to bid first, want 1", bidders)
+	}
+}
+
+func TestOffersFinished(t *testing.T) {
+	tt := newTestTable(t)
+
+	playerIDs := []int{
+		tt.connect(t, models.RolePlayer).setName("ana"),
+		tt.connect(t, models.RolePlayer).setName("bo"),
+	}
+
+	if !tt.svc.startGame() {
+		t.Fatal("game did not start")
+	}
+
+	var finished []models.OffersFinishedEvent
+	waitFor(t, "the offers to finish", func() bool {
+		finished = nil
+		for _, playerID := range playerIDs {
+			finished = append(finished, eventsOf[models.OffersFinishedEvent](tt.recorder.EventsForPlayer(playerID))...)
+		}
+		return len(finished) > 0
+	})
+
+	if got := finished[0].Type; got != models.EventTypeOffersFinished {
+		t.Errorf("offers_finished sent as %q, want %q", got, models.EventTypeOffersFinished)
+	}
+}
+
+func TestBidMustBeInHand(t *testing.T) {
+	tt := newTestTable(t)
+	tt.svc.settings.Timeouts.ChooseBid = 5000
+	ctx := context.Background()
+
+	clients := map[int]*testClient{}
+	for _, name := range []string{"ana", "bo"} {
+		client := tt.connect(t, models.RolePlayer)
+		clients[client.setName(name)] = client
+	}
+
+	if !tt.svc.startGame() {
+		t.Fatal("game did not start")
+	}
+	waitFor(t, "a player to bid", func() bool {
+		return tt.svc.currentPhase() == models.PhaseBid
+	})
+
+	currentPlayerID, err := tt.repo.GetCurrentPlayerID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var otherHand []models.Card
+	for playerID := range clients {
+		if playerID != currentPlayerID {
+			if otherHand, err = tt.repo.GetPlayerHand(ctx, playerID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	bid := models.BidSelected{Card: otherHand[0]}
+	if code := clients[currentPlayerID].sendError(models.EventTypeBidSelected, bid); code != models.ErrorCodeInvalidPayload {
+		t.Errorf("bidding a card of another hand got %q, want %q", code, models.ErrorCodeInvalidPayload)
+	}
+}
+
+func TestAFKPlayerCanBidAgain(t *testing.T) {
+	tt := newTestTable(t)
+	tt.svc.settings.Timeouts.BetweenActions = 5000
+	ctx := context.Background()
+
+	clients := map[int]*testClient{}
+	for _, name := range []string{"ana", "bo"} {
+		client := tt.connect(t, models.RolePlayer)
+		clients[client.setName(name)] = client
+	}
+	for playerID := range clients {
+		tt.svc.afk.away = append(tt.svc.afk.away, playerID)
+	}
+
+	if !tt.svc.startGame() {
+		t.Fatal("game did not start")
+	}
+	waitFor(t, "a player to bid", func() bool {
+		return tt.svc.currentPhase() == models.PhaseBid
+	})
+
+	currentPlayerID, err := tt.repo.GetCurrentPlayerID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hand, err := tt.repo.GetPlayerHand(ctx, currentPlayerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clients[currentPlayerID].send(models.EventTypeBidSelected, models.BidSelected{Card: hand[0]})
+	waitFor(t, "the bid to be shown", func() bool {
+		return tt.svc.currentPhase() != models.PhaseBid
+	})
+
+	if tt.svc.afk.isAFK(currentPlayerID) {
+		t.Error("player still afk after bidding")
+	}
+	for _, event := range eventsOf[models.AutoActionEvent](tt.recorder.EventsForPlayer(currentPlayerID)) {
+		if action := event.EventData.Action; action == models.AutoActionBid || action == models.AutoActionEndRound {
+			t.Errorf("bot played %s for a player who bid", action)
+		}
+	}
+}
+
+func TestSnapshotShowsOwnOffer(t *testing.T) {
+	tt := newTestTable(t)
+	tt.svc.settings.TableSize = 3
+	tt.svc.settings.Timeouts.ChooseOffer = 5000
+	ctx := context.Background()
+
+	tokens := map[int]string{}
+	clients := map[int]*testClient{}
+	for _, name := range []string{"ana", "bo", "cy"} {
+		client := tt.connect(t, models.RolePlayer)
+		client.send(models.EventTypeSetName, models.SetName{Name: name})
+
+		var response models.SetNameResponse
+		client.read(models.EventTypeSetNameResponse, &response)
+		tokens[response.AssignedPlayerID] = response.ResumeToken
+		clients[response.AssignedPlayerID] = client
+	}
+
+	if !tt.svc.startGame() {
+		t.Fatal("game did not start")
+	}
+	waitFor(t, "the players to offer", func() bool {
+		return tt.svc.currentPhase() == models.PhaseOffers
+	})
+
+	currentPlayerID, err := tt.repo.GetCurrentPlayerID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One of the other players offers, the last one is still thinking.
+	var offererID int
+	for playerID := range clients {
+		if playerID != currentPlayerID {
+			offererID = playerID
+			break
+		}
+	}
+	hand, err := tt.repo.GetPlayerHand(ctx, offererID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clients[offererID].send(models.EventTypeOfferSelected, models.OfferSelected{Card: hand[0]})
+	waitFor(t, "the offer to be recorded", func() bool {
+		tt.svc.turn.mu.Lock()
+		defer tt.svc.turn.mu.Unlock()
+		return len(tt.svc.turn.offers) > 0
+	})
+
+	resumed := tt.connect(t, models.RolePlayer)
+	var snapshot models.StateSnapshot
+	resumed.read(models.EventTypeStateSnapshot, &snapshot) // the one of the handshake
+	resumed.send(models.EventTypeResume, models.Resume{ResumeToken: tokens[offererID]})
+	resumed.read(models.EventTypeStateSnapshot, &snapshot)
+	if snapshot.Phase != models.PhaseOffers {
+		t.Fatalf("snapshot taken in %s, want %s", snapshot.Phase, models.PhaseOffers)
+	}
+	if snapshot.OwnOffer == nil || snapshot.OwnOffer.Card != hand[0] {
+		t.Errorf("snapshot has own offer %v, want %v", snapshot.OwnOffer, hand[0])
+	}
+	if snapshot.Deadline == 0 || snapshot.Timeout == 0 {
+		t.Errorf("snapshot has deadline %d and timeout %d while the offers are timed", snapshot.Deadline, snapshot.Timeout)
+	}
+}
+
+func TestNewGameResetsScores(t *testing.T) {
+	tt := newTestTable(t)
+	tt.svc.settings.Timeouts.ChooseBid = 5000
+	ctx := context.Background()
+
+	var last []models.Score
+	for _, name := range []string{"ana", "bo"} {
+		playerID := tt.connect(t, models.RolePlayer).setName(name)
+		last = append(last, models.Score{PlayerID: playerID, Points: 5000})
+	}
+	if err := tt.repo.SetPlayerScores(ctx, last); err != nil {
+		t.Fatal(err)
+	}
+
+	if !tt.svc.startGame() {
+		t.Fatal("game did not start")
+	}
+	waitFor(t, "a player to bid", func() bool {
+		return tt.svc.currentPhase() == models.PhaseBid
+	})
+
+	scores, err := tt.repo.GetPlayerScores(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, score := range scores {
+		if score.Points != 0 {
+			t.Errorf("player %d starts the game with %d points, want 0", score.PlayerID, score.Points)
+		}
+	}
+}
+
+func TestScores(t *testing.T) {
+	tt := newTestTable(t)
+	tt.svc.settings.Points = testPoints
+	ctx := context.Background()
+
+	hands := []struct {
+		name   string
+		hand   []models.Card
+		points int
+	}{
+		// A full house.
+		{"ana", []models.Card{realCard(1, 1), realCard(1, 2), realCard(1, 3), realCard(2, 1), realCard(2, 2)}, 3500},
+		// One of each, with one fake.
+		{"bo", []models.Card{realCard(1, 4), realCard(2, 3), realCard(3, 1), realCard(4, 1), fakeCard(4)}, 3750},
+	}
+
+	want := map[int]int{}
+	for _, h := range hands {
+		playerID, err := tt.repo.NewPlayer(ctx, h.name, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tt.repo.SetPlayerHand(ctx, playerID, h.hand); err != nil {
+			t.Fatal(err)
+		}
+		want[playerID] = h.points
+		tt.svc.seats = append(tt.svc.seats, playerID)
+	}
+
+	// A seated player who left still has their hand played by the bot, and
+	// scored.
+	if err := tt.repo.ClosePlayer(ctx, tt.svc.seats[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tt.svc.endOfRound(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for playerID := range want {
+		if got := eventsOf[models.UpdateScoreEvent](tt.recorder.EventsForPlayer(playerID)); len(got) != 0 {
+			t.Errorf("player %d got update_score, only the table should", playerID)
+		}
+	}
+
+	updates := eventsOf[models.UpdateScoreEvent](tt.recorder.EventsForHubs())
+	if len(updates) != 1 {
+		t.Fatalf("hubs got %d update_score, want 1", len(updates))
+	}
+
+	payload, err := json.Marshal(updates[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(payload), "is_real") {
+		t.Errorf("update_score shows whether cards are real: %s", payload)
+	}
+
+	if got := len(updates[0].EventData.Scores); got != len(want) {
+		t.Errorf("update_score has %d scores, want %d", got, len(want))
+	}
+	for _, score := range updates[0].EventData.Scores {
+		if score.Points != want[score.PlayerID] {
+			t.Errorf("player %d scored %d, want %d", score.PlayerID, score.Points, want[score.PlayerID])
+		}
+	}
+
+	scores, err := tt.repo.GetPlayerScoresOf(ctx, tt.svc.seats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, score := range scores {
+		if score.Points != want[score.PlayerID] {
+			t.Errorf("player %d has %d points saved, want %d", score.PlayerID, score.Points, want[score.PlayerID])
+		}
+	}
+}
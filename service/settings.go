@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Jubris-Knifes/wgj25-back/config"
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/Jubris-Knifes/wgj25-back/transport"
+	"github.com/olahol/melody"
+)
+
+// defaultSettings are the settings every table starts with.
+func defaultSettings() models.Settings {
+	conf := config.Get()
+
+	return models.Settings{
+		Timeouts: models.TimeoutSettings{
+			ChooseBid:          conf.Timeouts.PlayerChooseBidMilliseconds,
+			ShowBid:            conf.Timeouts.ShowBidMilliseconds,
+			ChooseOffer:        conf.Timeouts.PlayerChooseOfferMilliseconds,
+			ShowOffer:          conf.Timeouts.ShowOfferMilliseconds,
+			BetweenActions:     conf.Timeouts.TimeBetweenActionsMilliseconds,
+			OffersFinished:     conf.Timeouts.OffersFinishedMilliseconds,
+			ShowSelectedOffer:  conf.Timeouts.ShowSelectedOffer,
+			PrepareForNextTurn: conf.Timeouts.PrepareForNextTurnMilliseconds,
+			EndOfRound:         conf.Timeouts.EndOfRoundScreen,
+			UpdateScore:        conf.Timeouts.UpdateScoreScreen,
+			SumScore:           conf.Timeouts.SumScore,
+		},
+		Points: models.PointSettings{
+			FakePoker:    conf.Points.FakePoker,
+			Poker:        conf.Points.Poker,
+			OneOfEach:    conf.Points.OneOfEach,
+			FullHouse:    conf.Points.FullHouse,
+			ThreeOfAKind: conf.Points.ThreeOfAKind,
+			TwoPair:      conf.Points.TwoPair,
+			Pair:         conf.Points.Pair,
+			FakeOne:      conf.Points.FakeOne,
+			FakeTwo:      conf.Points.FakeTwo,
+			FakeThree:    conf.Points.FakeThree,
+		},
+		EndCondition: models.EndCondition{
+			Rounds:       conf.Table.Rounds,
+			TargetPoints: conf.Table.TargetPoints,
+		},
+		TableSize: conf.Table.Size,
+		Ruleset:   models.Ruleset(conf.Table.Ruleset),
+	}
+}
+
+func milliseconds(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+// rules returns the settings of the table. They can't change while a game is
+// running, so the game loop reads them as it goes.
+func (s *service) rules() models.Settings {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+
+	return s.settings
+}
+
+// controlsTable reports whether the session may run the table: the hub, or
+// the player hosting it.
+func (s *service) controlsTable(session *melody.Session) bool {
+	if sessionRole(session) == models.RoleHub {
+		return true
+	}
+
+	playerID, ok := transport.SessionPlayerID(session)
+	return ok && playerID == s.lobby.host()
+}
+
+func (s *service) handleSettingsUpdateEvent(session *melody.Session, update models.SettingsUpdate) error {
+	ctx := session.Request.Context()
+
+	if !s.controlsTable(session) {
+		return newClientError(models.ErrorCodeNotAllowed, "only the host can change the settings")
+	}
+
+	s.gameMu.Lock()
+	if s.gameCancel != nil || s.restored != nil {
+		s.gameMu.Unlock()
+		return ErrGameAlreadyRunning
+	}
+	s.settings = update.Settings
+	s.gameMu.Unlock()
+
+	s.log.InfoContext(ctx, "table settings changed", "settings", update.Settings)
+	s.broadcastSettings(ctx)
+
+	return nil
+}
+
+func (s *service) broadcastSettings(ctx context.Context) {
+	event := models.SettingsEvent{
+		Type:      models.EventTypeSettings,
+		EventData: s.rules(),
+	}
+
+	if err := s.publish(ctx, everyone(event)); err != nil {
+		s.log.ErrorContext(ctx, "failed to broadcast settings event", "error", err)
+	}
+}
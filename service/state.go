@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/olahol/melody"
+)
+
+// turnState is what the current turn has settled so far. It only lives in
+// the game loop otherwise, and joining clients need it to catch up.
+type turnState struct {
+	mu             sync.Mutex
+	bid            *models.Card
+	playersOffered []int
+	offers         []models.PlayerOffer
+}
+
+func (t *turnState) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.bid = nil
+	t.playersOffered = nil
+	t.offers = nil
+}
+
+func (t *turnState) setBid(bid models.Card) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.bid = &bid
+}
+
+func (t *turnState) setPlayersOffered(playerIDs []int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.playersOffered = slices.Clone(playerIDs)
+}
+
+// addOffer records an offer as it comes in, in place of any earlier one of
+// the same player.
+func (t *turnState) addOffer(offer models.PlayerOffer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.offers = slices.DeleteFunc(t.offers, func(o models.PlayerOffer) bool { return o.PlayerID == offer.PlayerID })
+	t.offers = append(t.offers, offer)
+}
+
+func (t *turnState) setOffers(offers []models.PlayerOffer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.offers = slices.Clone(offers)
+}
+
+// stateSnapshot builds the snapshot sent to a session joining the table.
+// Players see their own hand and, when it's their turn, their bid and the
+// faces of the offers they got; everyone else only learns that they were
+// made. See view.go.
+func (s *service) stateSnapshot(ctx context.Context, session *melody.Session) (models.StateSnapshot, error) {
+	paused, _, _, _ := s.clock.state()
+	snapshot := models.StateSnapshot{
+		Phase:  s.currentPhase(),
+		Paused: paused,
+	}
+	if left, ok := s.clock.timeLeft(); ok && s.isGameRunning() {
+		snapshot.Timeout = left.Milliseconds()
+		if !paused {
+			snapshot.Deadline = deadlineIn(left)
+		}
+	}
+
+	roster, err := s.roster(ctx)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.Roster = roster
+	snapshot.HostPlayerID = s.lobby.host()
+	snapshot.Settings = s.rules()
+	if room, ok := s.hubRoom(session); ok {
+		snapshot.Room = &room
+	}
+
+	if snapshot.Scores, err = s.repo.GetPlayerScores(ctx); err != nil {
+		return snapshot, err
+	}
+
+	if snapshot.Phase == models.PhaseLobby {
+		return snapshot, nil
+	}
+
+	snapshot.AFKPlayerIDs = s.afk.players()
+
+	currentPlayerID, err := s.repo.GetCurrentPlayerID(ctx)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return snapshot, err
+	default:
+		snapshot.CurrentPlayerID = &currentPlayerID
+	}
+
+	playerID, isPlayer := getAs[int](s.log, session, PlayerIDKey)
+	if isPlayer {
+		if snapshot.Hand, err = s.repo.GetPlayerHand(ctx, playerID); err != nil {
+			return snapshot, err
+		}
+	}
+	isCurrentPlayer := isPlayer && snapshot.CurrentPlayerID != nil && *snapshot.CurrentPlayerID == playerID
+
+	s.turn.mu.Lock()
+	defer s.turn.mu.Unlock()
+
+	snapshot.BidPlaced = s.turn.bid != nil
+	snapshot.PlayersOffered = slices.Clone(s.turn.playersOffered)
+	if isCurrentPlayer {
+		snapshot.Bid = s.turn.bid
+		// The offers are only shown once every player made theirs.
+		if snapshot.Phase != models.PhaseOffers {
+			snapshot.Offers = offerFaces(s.turn.offers)
+		}
+	} else if isPlayer {
+		i := slices.IndexFunc(s.turn.offers, func(offer models.PlayerOffer) bool { return offer.PlayerID == playerID })
+		if i >= 0 {
+			offer := s.turn.offers[i]
+			snapshot.OwnOffer = &offer
+		}
+	}
+
+	return snapshot, nil
+}
+
+func (s *service) sendStateSnapshot(ctx context.Context, session *melody.Session) error {
+	snapshot, err := s.stateSnapshot(ctx, session)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to build state snapshot", "error", err)
+		return err
+	}
+
+	event := models.StateSnapshotEvent{
+		Type:      models.EventTypeStateSnapshot,
+		EventData: snapshot,
+	}
+
+	return s.write(session, event)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+)
+
+// Who learns what at the table. Every game event goes through publish with a
+// view deciding, for each kind of viewer, which version of it they get:
+//
+//	event                   current player   other players   hub, spectators
+//	dealing_cards, game_*   yes              yes             yes
+//	cards_dealt/update      own hand         own hand        -
+//	choose_bid              yes              -               -
+//	show_back_of_card_bid   -                -               yes
+//	bid_selected            own bid          -               -
+//	choose_Offer            -                yes             yes
+//	Offer_selected          -                own offer       -
+//	made_offer              -                -               yes
+//	offers_finished         faces only       -               -
+//	select_offer_choices    faces only       -               -
+//	select_offer_chosen     -                -               yes
+//	prepare_for_next_turn   next bidder      -               -
+//	end of round scores     -                -               yes, faces only
+//	player_afk              that player      that player     yes
+//	auto_action             own card         own card        yes, no card
+//	connection_quality      -                -               hub only
+//	room                    -                -               its own hub
+//
+// Whether a card is real is only ever shown to the player holding it. Events
+// the table sees a card in get a copy of their own, made with
+// withTableCopy or from card faces, with that left out.
+
+type viewer struct {
+	role     models.Role
+	playerID int
+	current  bool
+}
+
+// view returns the version of an event v may see, and false if v must not
+// get it at all.
+type view func(v viewer) (any, bool)
+
+func everyone(event any) view {
+	return func(viewer) (any, bool) {
+		return event, true
+	}
+}
+
+// toTable shows event on the hub and to spectators, who watch the same
+// screen.
+func toTable(event any) view {
+	return func(v viewer) (any, bool) {
+		return event, v.role != models.RolePlayer
+	}
+}
+
+func toHub(event any) view {
+	return func(v viewer) (any, bool) {
+		return event, v.role == models.RoleHub
+	}
+}
+
+func toPlayer(playerID int, event any) view {
+	return func(v viewer) (any, bool) {
+		return event, v.role == models.RolePlayer && v.playerID == playerID
+	}
+}
+
+func toCurrentPlayer(event any) view {
+	return func(v viewer) (any, bool) {
+		return event, v.current
+	}
+}
+
+// toTableAndPlayers shows event on the table and to the given players.
+func toTableAndPlayers(playerIDs []int, event any) view {
+	return func(v viewer) (any, bool) {
+		if v.role != models.RolePlayer {
+			return event, true
+		}
+
+		for _, playerID := range playerIDs {
+			if playerID == v.playerID {
+				return event, true
+			}
+		}
+
+		return nil, false
+	}
+}
+
+// withTableCopy shows event to the viewers of full, and tableCopy, which
+// leaves out what only players may see, to the hub and spectators.
+func withTableCopy(full view, tableCopy any) view {
+	return func(v viewer) (any, bool) {
+		if v.role != models.RolePlayer {
+			return tableCopy, true
+		}
+
+		return full(v)
+	}
+}
+
+func handFaces(hand []models.Card) []models.CardFace {
+	faces := make([]models.CardFace, 0, len(hand))
+	for _, card := range hand {
+		faces = append(faces, card.Face())
+	}
+
+	return faces
+}
+
+// offerFaces hides whether the offered cards are real from the player
+// choosing between them.
+func offerFaces(offers []models.PlayerOffer) []models.OfferFace {
+	faces := make([]models.OfferFace, 0, len(offers))
+	for _, offer := range offers {
+		faces = append(faces, models.OfferFace{
+			PlayerID: offer.PlayerID,
+			Card:     offer.Card.Face(),
+		})
+	}
+
+	return faces
+}
+
+// publish sends every viewer at the table its version of an event.
+func (s *service) publish(ctx context.Context, view view) error {
+	playerIDs, err := s.repo.GetActivePlayerIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	currentPlayerID, err := s.repo.GetCurrentPlayerID(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	var errs []error
+	for _, playerID := range playerIDs {
+		v := viewer{role: models.RolePlayer, playerID: playerID, current: playerID == currentPlayerID}
+		if event, ok := view(v); ok {
+			errs = append(errs, s.transport.SendToPlayer(playerID, event))
+		}
+	}
+
+	if event, ok := view(viewer{role: models.RoleHub}); ok {
+		errs = append(errs, s.transport.SendToHubs(event))
+	}
+
+	if event, ok := view(viewer{role: models.RoleSpectator}); ok {
+		errs = append(errs, s.transport.SendToSpectators(event))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,155 @@
+package transport
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/gorilla/websocket"
+	"github.com/olahol/melody"
+)
+
+const (
+	LatencyKey = "latency"
+
+	// latencyWindow is how many round trips the rolling stats cover.
+	latencyWindow = 20
+
+	pingWriteWait = 5 * time.Second
+)
+
+// latency keeps the last round trips of a session, measured with websocket
+// pings.
+type latency struct {
+	mu         sync.Mutex
+	pingSentAt time.Time
+	samples    []time.Duration
+	next       int
+	timeouts   int
+}
+
+// add records a round trip, dropping the oldest once the window is full.
+func (l *latency) add(rtt time.Duration) {
+	if len(l.samples) < latencyWindow {
+		l.samples = append(l.samples, rtt)
+		return
+	}
+	l.samples[l.next] = rtt
+	l.next = (l.next + 1) % latencyWindow
+}
+
+// latencyCreator keeps two callers from creating stats for the same session.
+var latencyCreator sync.Mutex
+
+func latencyFor(session *melody.Session) *latency {
+	latencyCreator.Lock()
+	defer latencyCreator.Unlock()
+
+	value, _ := session.Get(LatencyKey)
+	if l, ok := value.(*latency); ok {
+		return l
+	}
+
+	l := &latency{samples: make([]time.Duration, 0, latencyWindow)}
+	session.Set(LatencyKey, l)
+
+	return l
+}
+
+// Ping sends a websocket ping to the session. Only one ping is measured at a
+// time, a session that hasn't answered the last one is not pinged again until
+// timeout has passed. The lost ping then counts as a round trip of however
+// long it went unanswered.
+func Ping(session *melody.Session, timeout time.Duration) error {
+	if session.IsClosed() {
+		return melody.ErrSessionClosed
+	}
+
+	l := latencyFor(session)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if !l.pingSentAt.IsZero() {
+		waited := now.Sub(l.pingSentAt)
+		if waited < timeout {
+			return nil
+		}
+
+		l.add(waited)
+		l.timeouts++
+		l.pingSentAt = time.Time{}
+	}
+
+	if err := session.WebsocketConnection().WriteControl(websocket.PingMessage, nil, now.Add(pingWriteWait)); err != nil {
+		return err
+	}
+	l.pingSentAt = now
+
+	return nil
+}
+
+// Pong must be called whenever a session answers a ping. Melody doesn't tell
+// its own keepalive pings apart from ours, so a pong with no ping of ours
+// pending is ignored.
+func Pong(session *melody.Session) {
+	l := latencyFor(session)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.pingSentAt.IsZero() {
+		return
+	}
+
+	l.add(time.Since(l.pingSentAt))
+	l.pingSentAt = time.Time{}
+}
+
+// Latency returns the rolling round trip stats of the session, and false if
+// it never answered a ping.
+func Latency(session *melody.Session) (models.LatencyStats, bool) {
+	value, _ := session.Get(LatencyKey)
+	l, ok := value.(*latency)
+	if !ok {
+		return models.LatencyStats{}, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.samples) == 0 {
+		return models.LatencyStats{}, false
+	}
+
+	last := l.samples[len(l.samples)-1]
+	if len(l.samples) == latencyWindow {
+		last = l.samples[(l.next+latencyWindow-1)%latencyWindow]
+	}
+
+	var sum, highest time.Duration
+	for _, sample := range l.samples {
+		sum += sample
+		highest = max(highest, sample)
+	}
+	average := sum / time.Duration(len(l.samples))
+
+	// Jitter is the standard deviation of the round trips.
+	var variance float64
+	for _, sample := range l.samples {
+		diff := float64(sample - average)
+		variance += diff * diff
+	}
+	jitter := time.Duration(math.Sqrt(variance / float64(len(l.samples))))
+
+	return models.LatencyStats{
+		LastMilliseconds:    last.Milliseconds(),
+		AverageMilliseconds: average.Milliseconds(),
+		JitterMilliseconds:  jitter.Milliseconds(),
+		MaxMilliseconds:     highest.Milliseconds(),
+		Samples:             len(l.samples),
+		Timeouts:            l.timeouts,
+	}, true
+}
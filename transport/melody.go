@@ -0,0 +1,69 @@
+package transport
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/olahol/melody"
+)
+
+// Melody delivers events over the websocket sessions in a registry.
+type Melody struct {
+	log      *slog.Logger
+	sessions *Registry
+}
+
+func NewMelody(logger *slog.Logger, sessions *Registry) *Melody {
+	return &Melody{
+		log:      logger,
+		sessions: sessions,
+	}
+}
+
+func (t *Melody) SendToPlayer(playerID int, event any) error {
+	return t.Send(t.sessions.Player(playerID), event)
+}
+
+func (t *Melody) SendToHubs(event any) error {
+	return t.Send(t.sessions.Role(models.RoleHub), event)
+}
+
+func (t *Melody) SendToSpectators(event any) error {
+	return t.Send(t.sessions.Role(models.RoleSpectator), event)
+}
+
+func (t *Melody) Broadcast(event any) error {
+	return t.Send(t.sessions.All(), event)
+}
+
+// Send queues event for every open session given, encoding it once per codec
+// in use.
+func (t *Melody) Send(sessions []*melody.Session, event any) error {
+	eventType := eventTypeOf(event)
+	payloads := map[string][]byte{}
+	var errs []error
+	for _, session := range sessions {
+		if session.IsClosed() {
+			continue
+		}
+
+		c := SessionCodec(session)
+		payload, ok := payloads[c.Name()]
+		if !ok {
+			var err error
+			payload, err = c.Marshal(event)
+			if err != nil {
+				t.log.Error("failed to marshal event", "error", err, "codec", c.Name())
+				return err
+			}
+			payloads[c.Name()] = payload
+		}
+
+		if err := writeEncoded(session, c, eventType, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,239 @@
+package transport
+
+import (
+	"errors"
+	"expvar"
+	"slices"
+	"sync"
+
+	"github.com/Jubris-Knifes/wgj25-back/config"
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/olahol/melody"
+)
+
+const (
+	OutboundQueueKey = "outbound_queue"
+
+	// CloseCodeTooSlow closes sessions that can't keep up with critical
+	// events.
+	CloseCodeTooSlow = 4003
+
+	// inFlightWindow is how many messages are handed to melody at once. The
+	// rest wait in our queue, where they can still be merged or dropped.
+	inFlightWindow = 4
+)
+
+var ErrSessionTooSlow = errors.New("session too slow, disconnected")
+
+var (
+	outboundQueued     = expvar.NewInt("outbound_queued")
+	outboundDropped    = expvar.NewMap("outbound_dropped")
+	outboundMerged     = expvar.NewMap("outbound_merged")
+	slowSessionsKicked = expvar.NewInt("slow_sessions_disconnected")
+
+	// outboundQueueCreator keeps two senders from creating a queue for the
+	// same session.
+	outboundQueueCreator sync.Mutex
+)
+
+// Policy is what happens to an event queued for a session that can't keep up.
+type Policy int
+
+const (
+	// Critical events are never dropped. A session too slow to take them is
+	// disconnected, and gets the state again when it reconnects.
+	Critical Policy = iota
+	// Droppable events are dropped when the queue is full.
+	Droppable
+	// Mergeable events replace the one of the same type still queued, since
+	// only the latest matters.
+	Mergeable
+)
+
+// eventPolicies lists the events that are not critical.
+var eventPolicies = map[models.EventType]Policy{
+	models.EventTypeMadeOffer:         Mergeable,
+	models.EventTypePong:              Droppable,
+	models.EventTypeConnectionQuality: Mergeable,
+}
+
+type eventTyper interface {
+	EventType() models.EventType
+}
+
+func policyOf(eventType models.EventType) Policy {
+	if policy, ok := eventPolicies[eventType]; ok {
+		return policy
+	}
+
+	return Critical
+}
+
+type outbound struct {
+	eventType models.EventType
+	policy    Policy
+	payload   []byte
+	binary    bool
+}
+
+// outboundQueue holds the messages of a session until melody is ready for
+// them, so slow clients are noticed instead of melody silently dropping what
+// overflows its buffer.
+type outboundQueue struct {
+	mu       sync.Mutex
+	session  *melody.Session
+	depth    int
+	items    []outbound
+	inFlight int
+	closed   bool
+}
+
+func queueFor(session *melody.Session) *outboundQueue {
+	outboundQueueCreator.Lock()
+	defer outboundQueueCreator.Unlock()
+
+	value, _ := session.Get(OutboundQueueKey)
+	if queue, ok := value.(*outboundQueue); ok {
+		return queue
+	}
+
+	queue := &outboundQueue{
+		session: session,
+		depth:   config.Get().Limits.OutboundQueueDepth,
+	}
+	session.Set(OutboundQueueKey, queue)
+
+	return queue
+}
+
+// QueueLength returns how many messages are waiting to be sent to the session.
+func QueueLength(session *melody.Session) int {
+	value, _ := session.Get(OutboundQueueKey)
+	queue, ok := value.(*outboundQueue)
+	if !ok {
+		return 0
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	return len(queue.items)
+}
+
+// MessageSent must be called whenever melody sent a message to the session,
+// to hand it the next one.
+func MessageSent(session *melody.Session) {
+	queue := queueFor(session)
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if queue.inFlight > 0 {
+		queue.inFlight--
+	}
+	queue.flush()
+}
+
+// discardQueue drops whatever is still queued for a closed session.
+func discardQueue(session *melody.Session) {
+	value, _ := session.Get(OutboundQueueKey)
+	queue, ok := value.(*outboundQueue)
+	if !ok {
+		return
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	queue.closed = true
+	outboundQueued.Add(-int64(len(queue.items)))
+	queue.items = nil
+}
+
+func (q *outboundQueue) push(item outbound) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.session.IsClosed() {
+		return melody.ErrSessionClosed
+	}
+
+	if item.policy == Mergeable {
+		i := slices.IndexFunc(q.items, func(queued outbound) bool { return queued.eventType == item.eventType })
+		if i >= 0 {
+			q.items[i] = item
+			outboundMerged.Add(string(item.eventType), 1)
+			return nil
+		}
+	}
+
+	if len(q.items) >= q.depth {
+		if item.policy == Droppable {
+			outboundDropped.Add(string(item.eventType), 1)
+			return nil
+		}
+
+		if !q.evict() {
+			return q.tooSlow()
+		}
+	}
+
+	q.items = append(q.items, item)
+	outboundQueued.Add(1)
+	q.flush()
+
+	return nil
+}
+
+// evict makes room in a full queue by dropping its oldest event that is not
+// critical.
+func (q *outboundQueue) evict() bool {
+	i := slices.IndexFunc(q.items, func(queued outbound) bool { return queued.policy != Critical })
+	if i < 0 {
+		return false
+	}
+
+	outboundDropped.Add(string(q.items[i].eventType), 1)
+	q.items = slices.Delete(q.items, i, i+1)
+	outboundQueued.Add(-1)
+
+	return true
+}
+
+// tooSlow disconnects a session whose queue is full of critical events.
+func (q *outboundQueue) tooSlow() error {
+	q.closed = true
+	outboundQueued.Add(-int64(len(q.items)))
+	q.items = nil
+	slowSessionsKicked.Add(1)
+
+	msg := melody.FormatCloseMessage(CloseCodeTooSlow, "connection too slow to keep up")
+	if err := q.session.CloseWithMsg(msg); err != nil {
+		return errors.Join(ErrSessionTooSlow, err)
+	}
+
+	return ErrSessionTooSlow
+}
+
+func (q *outboundQueue) flush() {
+	for q.inFlight < inFlightWindow && len(q.items) > 0 {
+		item := q.items[0]
+		q.items = q.items[1:]
+		outboundQueued.Add(-1)
+
+		var err error
+		if item.binary {
+			err = q.session.WriteBinary(item.payload)
+		} else {
+			err = q.session.Write(item.payload)
+		}
+		if err != nil {
+			q.closed = true
+			outboundQueued.Add(-int64(len(q.items)))
+			q.items = nil
+			return
+		}
+
+		q.inFlight++
+	}
+}
@@ -0,0 +1,111 @@
+package transport
+
+import (
+	"slices"
+	"sync"
+)
+
+type RecipientKind int
+
+const (
+	ToPlayer RecipientKind = iota
+	ToHubs
+	ToSpectators
+	ToEveryone
+)
+
+type Recipient struct {
+	Kind     RecipientKind
+	PlayerID int
+}
+
+type Delivery struct {
+	Recipient Recipient
+	Event     any
+}
+
+// Recorder is an in-memory Transport that keeps every event sent through it,
+// so game logic can be checked by what each recipient got.
+type Recorder struct {
+	mu         sync.Mutex
+	deliveries []Delivery
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) record(recipient Recipient, event any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deliveries = append(r.deliveries, Delivery{Recipient: recipient, Event: event})
+
+	return nil
+}
+
+func (r *Recorder) SendToPlayer(playerID int, event any) error {
+	return r.record(Recipient{Kind: ToPlayer, PlayerID: playerID}, event)
+}
+
+func (r *Recorder) SendToHubs(event any) error {
+	return r.record(Recipient{Kind: ToHubs}, event)
+}
+
+func (r *Recorder) SendToSpectators(event any) error {
+	return r.record(Recipient{Kind: ToSpectators}, event)
+}
+
+func (r *Recorder) Broadcast(event any) error {
+	return r.record(Recipient{Kind: ToEveryone}, event)
+}
+
+// Deliveries returns everything sent so far, in order.
+func (r *Recorder) Deliveries() []Delivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.deliveries)
+}
+
+// EventsForPlayer returns the events the player got, broadcasts included.
+func (r *Recorder) EventsForPlayer(playerID int) []any {
+	return r.events(func(recipient Recipient) bool {
+		return recipient.Kind == ToEveryone || (recipient.Kind == ToPlayer && recipient.PlayerID == playerID)
+	})
+}
+
+// EventsForHubs returns the events hubs got, broadcasts included.
+func (r *Recorder) EventsForHubs() []any {
+	return r.events(func(recipient Recipient) bool {
+		return recipient.Kind == ToEveryone || recipient.Kind == ToHubs
+	})
+}
+
+// EventsForSpectators returns the events spectators got, broadcasts included.
+func (r *Recorder) EventsForSpectators() []any {
+	return r.events(func(recipient Recipient) bool {
+		return recipient.Kind == ToEveryone || recipient.Kind == ToSpectators
+	})
+}
+
+func (r *Recorder) events(match func(Recipient) bool) []any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []any
+	for _, delivery := range r.deliveries {
+		if match(delivery.Recipient) {
+			events = append(events, delivery.Event)
+		}
+	}
+
+	return events
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deliveries = nil
+}
@@ -0,0 +1,146 @@
+package transport
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/olahol/melody"
+)
+
+type sessionSet map[*melody.Session]struct{}
+
+func (set sessionSet) list() []*melody.Session {
+	sessions := make([]*melody.Session, 0, len(set))
+	for session := range set {
+		sessions = append(sessions, session)
+	}
+
+	return sessions
+}
+
+// Registry keeps the open sessions indexed by player and role, so targeted
+// sends don't have to scan every connection. A player may have several
+// sessions open, one per tab or device.
+type Registry struct {
+	mu       sync.RWMutex
+	sessions sessionSet
+	byPlayer map[int]sessionSet
+	byRole   map[models.Role]sessionSet
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		sessions: sessionSet{},
+		byPlayer: map[int]sessionSet{},
+		byRole:   map[models.Role]sessionSet{},
+	}
+}
+
+// Add registers a new session. It has no role until SetRole is called.
+func (r *Registry) Add(session *melody.Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions[session] = struct{}{}
+}
+
+// Remove forgets the session. It returns the player the session belonged to,
+// if any, and whether that player has other sessions still open.
+func (r *Registry) Remove(session *melody.Session) (playerID int, stillConnected bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, session)
+	discardQueue(session)
+	removeFrom(r.byRole, SessionRole(session), session)
+
+	playerID, ok := SessionPlayerID(session)
+	if !ok {
+		return 0, false
+	}
+	removeFrom(r.byPlayer, playerID, session)
+
+	return playerID, len(r.byPlayer[playerID]) > 0
+}
+
+// SetRole records the role the session declared at handshake.
+func (r *Registry) SetRole(session *melody.Session, role models.Role) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removeFrom(r.byRole, SessionRole(session), session)
+	session.Set(RoleKey, role)
+	addIndex(r.byRole, role, session)
+}
+
+// SetPlayer binds the session to the player it joined as.
+func (r *Registry) SetPlayer(session *melody.Session, playerID int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if previous, ok := SessionPlayerID(session); ok {
+		removeFrom(r.byPlayer, previous, session)
+	}
+	session.Set(PlayerIDKey, playerID)
+	addIndex(r.byPlayer, playerID, session)
+}
+
+// Player returns the open sessions of a player.
+func (r *Registry) Player(playerID int) []*melody.Session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.byPlayer[playerID].list()
+}
+
+// Role returns the open sessions that declared role.
+func (r *Registry) Role(role models.Role) []*melody.Session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.byRole[role].list()
+}
+
+// All returns every open session, handshake done or not.
+func (r *Registry) All() []*melody.Session {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sessions.list()
+}
+
+// PlayerIDs returns the players with at least one open session, sorted.
+func (r *Registry) PlayerIDs() []int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	playerIDs := make([]int, 0, len(r.byPlayer))
+	for playerID := range r.byPlayer {
+		playerIDs = append(playerIDs, playerID)
+	}
+	slices.Sort(playerIDs)
+
+	return playerIDs
+}
+
+func addIndex[K comparable](index map[K]sessionSet, key K, session *melody.Session) {
+	set, ok := index[key]
+	if !ok {
+		set = sessionSet{}
+		index[key] = set
+	}
+	set[session] = struct{}{}
+}
+
+func removeFrom[K comparable](index map[K]sessionSet, key K, session *melody.Session) {
+	set, ok := index[key]
+	if !ok {
+		return
+	}
+
+	delete(set, session)
+	if len(set) == 0 {
+		delete(index, key)
+	}
+}
@@ -0,0 +1,77 @@
+package transport
+
+import (
+	"github.com/Jubris-Knifes/wgj25-back/codec"
+	"github.com/Jubris-Knifes/wgj25-back/models"
+	"github.com/olahol/melody"
+)
+
+// Session keys holding what the transport needs to know about a connection.
+const (
+	PlayerIDKey = "player_id"
+	RoleKey     = "role"
+	CodecKey    = "codec"
+)
+
+// Transport delivers game events to the clients at a table.
+type Transport interface {
+	SendToPlayer(playerID int, event any) error
+	SendToHubs(event any) error
+	SendToSpectators(event any) error
+	Broadcast(event any) error
+}
+
+// SessionPlayerID returns the id of the player behind the session, if it has
+// joined as one.
+func SessionPlayerID(session *melody.Session) (int, bool) {
+	value, _ := session.Get(PlayerIDKey)
+	playerID, ok := value.(int)
+	return playerID, ok
+}
+
+// SessionRole returns the role the session declared at handshake.
+func SessionRole(session *melody.Session) models.Role {
+	value, _ := session.Get(RoleKey)
+	role, _ := value.(models.Role)
+	return role
+}
+
+// SessionCodec returns the encoding negotiated with the session at handshake,
+// JSON until then.
+func SessionCodec(session *melody.Session) codec.Codec {
+	value, _ := session.Get(CodecKey)
+	if c, ok := value.(codec.Codec); ok {
+		return c
+	}
+
+	return codec.JSON
+}
+
+// Write queues event for a single session in its own encoding.
+func Write(session *melody.Session, event any) error {
+	c := SessionCodec(session)
+
+	payload, err := c.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return writeEncoded(session, c, eventTypeOf(event), payload)
+}
+
+func writeEncoded(session *melody.Session, c codec.Codec, eventType models.EventType, payload []byte) error {
+	return queueFor(session).push(outbound{
+		eventType: eventType,
+		policy:    policyOf(eventType),
+		payload:   payload,
+		binary:    c.Binary(),
+	})
+}
+
+func eventTypeOf(event any) models.EventType {
+	if e, ok := event.(eventTyper); ok {
+		return e.EventType()
+	}
+
+	return ""
+}